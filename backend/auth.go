@@ -0,0 +1,617 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/pbkdf2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// 登录会话有效期，API token 不过期
+	sessionTokenTTL  = 30 * 24 * time.Hour
+	pbkdf2Iterations = 100000
+	minPasswordLen   = 8
+)
+
+// 设置 AUTH_REQUIRED=true 后未登录的请求会被拒绝，否则匿名请求保持原有的单用户行为
+var authRequired = getEnv("AUTH_REQUIRED", "") == "true"
+
+// 用户
+type User struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	Username     string    `json:"username" gorm:"uniqueIndex"`
+	PasswordHash string    `json:"-"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// 登录会话或 API token，只保存哈希
+type AuthToken struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	UserID     uint       `json:"-" gorm:"index"`
+	Name       string     `json:"name"`
+	TokenHash  string     `json:"-" gorm:"uniqueIndex"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// 用户订阅的频道。频道和节目（音频、字幕）在用户之间共享
+type Subscription struct {
+	UserID    uint      `json:"user_id" gorm:"primaryKey;autoIncrement:false"`
+	ChannelID string    `json:"channel_id" gorm:"primaryKey"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// 用户对节目的个人数据
+type UserEpisode struct {
+	UserID      uint       `json:"user_id" gorm:"primaryKey;autoIncrement:false"`
+	EpisodeGUID string     `json:"episode_guid" gorm:"primaryKey"`
+	Played      bool       `json:"played"`
+	PlayedAt    *time.Time `json:"played_at"`
+	Starred     bool       `json:"starred"`
+	StarredAt   *time.Time `json:"starred_at"`
+	Archived    bool       `json:"archived"` // 已归档的节目默认不在列表中显示
+	Summary     string     `json:"summary" gorm:"type:text"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// 格式：pbkdf2-sha256$迭代次数$salt$hash
+func hashPassword(password string) (string, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := pbkdf2.Key([]byte(password), salt, pbkdf2Iterations, 32, sha256.New)
+	return fmt.Sprintf("pbkdf2-sha256$%d$%s$%s", pbkdf2Iterations,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+func verifyPassword(password, encoded string) bool {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 4 || parts[0] != "pbkdf2-sha256" {
+		return false
+	}
+	iterations, err := strconv.Atoi(parts[1])
+	if err != nil || iterations <= 0 {
+		return false
+	}
+	salt, err1 := base64.RawStdEncoding.DecodeString(parts[2])
+	expected, err2 := base64.RawStdEncoding.DecodeString(parts[3])
+	if err1 != nil || err2 != nil {
+		return false
+	}
+	key := pbkdf2.Key([]byte(password), salt, iterations, len(expected), sha256.New)
+	return subtle.ConstantTimeCompare(key, expected) == 1
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// 生成新 token，返回明文（只在创建时返回给客户端一次）
+func issueToken(userID uint, name string, ttl time.Duration) (string, *AuthToken, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", nil, err
+	}
+	token := hex.EncodeToString(raw)
+	record := &AuthToken{UserID: userID, Name: name, TokenHash: hashToken(token)}
+	if ttl > 0 {
+		expires := time.Now().Add(ttl)
+		record.ExpiresAt = &expires
+	}
+	if err := db.Create(record).Error; err != nil {
+		return "", nil, err
+	}
+	return token, record, nil
+}
+
+type authContextKey struct{}
+
+type authInfo struct {
+	User  *User
+	Token *AuthToken
+}
+
+func bearerToken(r *http.Request) string {
+	h := r.Header.Get("Authorization")
+	if len(h) > 7 && strings.EqualFold(h[:7], "Bearer ") {
+		return strings.TrimSpace(h[7:])
+	}
+	return ""
+}
+
+// 解析 Authorization 头中的 token
+func lookupToken(token string) (*authInfo, error) {
+	var record AuthToken
+	if db.Where("token_hash = ?", hashToken(token)).Limit(1).Find(&record).RowsAffected == 0 {
+		return nil, fmt.Errorf("invalid token")
+	}
+	now := time.Now()
+	if record.ExpiresAt != nil && record.ExpiresAt.Before(now) {
+		db.Delete(&record)
+		return nil, fmt.Errorf("token expired")
+	}
+	var user User
+	if db.Where("id = ?", record.UserID).Limit(1).Find(&user).RowsAffected == 0 {
+		return nil, fmt.Errorf("invalid token")
+	}
+	// 降低写入频率，每分钟最多更新一次
+	if record.LastUsedAt == nil || now.Sub(*record.LastUsedAt) > time.Minute {
+		db.Model(&AuthToken{}).Where("id = ?", record.ID).UpdateColumn("last_used_at", now)
+		record.LastUsedAt = &now
+	}
+	return &authInfo{User: &user, Token: &record}, nil
+}
+
+// 无需登录即可访问的接口
+func isPublicPath(path string) bool {
+	if !strings.HasPrefix(path, "/api/") {
+		return true
+	}
+	return path == "/api/auth/register" || path == "/api/auth/login"
+}
+
+// 认证中间件：携带 token 时解析当前用户，token 无效返回 401；
+// AUTH_REQUIRED 时未登录的 API 请求返回 401，否则已有用户后拒绝匿名的写请求
+func withAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "OPTIONS" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if token := bearerToken(r); token != "" {
+			info, err := lookupToken(token)
+			if err != nil {
+				enableCors(&w)
+				http.Error(w, "Unauthorized: "+err.Error(), http.StatusUnauthorized)
+				return
+			}
+			r = r.WithContext(context.WithValue(r.Context(), authContextKey{}, info))
+		} else if !isPublicPath(r.URL.Path) && (authRequired || (!isReadMethod(r.Method) && hasUsers())) {
+			enableCors(&w)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func isReadMethod(method string) bool {
+	return method == "GET" || method == "HEAD"
+}
+
+// 是否已有注册用户。有用户后匿名请求不能修改数据，也看不到任何频道
+func hasUsers() bool {
+	var count int64
+	db.Model(&User{}).Limit(1).Count(&count)
+	return count > 0
+}
+
+// 当前登录用户，匿名请求返回 nil
+func currentUser(r *http.Request) *User {
+	if info, ok := r.Context().Value(authContextKey{}).(*authInfo); ok {
+		return info.User
+	}
+	return nil
+}
+
+func currentToken(r *http.Request) *AuthToken {
+	if info, ok := r.Context().Value(authContextKey{}).(*authInfo); ok {
+		return info.Token
+	}
+	return nil
+}
+
+// 用户可见的频道。匿名时在没有用户前为全部频道，有用户后为空
+func userChannelsQuery(user *User) *gorm.DB {
+	if user == nil {
+		if hasUsers() {
+			return db.Model(&Channel{}).Where("1 = 0")
+		}
+		return db.Model(&Channel{})
+	}
+	return db.Model(&Channel{}).Where("id IN (?)",
+		db.Model(&Subscription{}).Select("channel_id").Where("user_id = ?", user.ID))
+}
+
+// 匿名用户只在没有用户前可以访问频道，与 withAuth 拒绝匿名写请求一致
+func canAccessChannel(user *User, channelID string) bool {
+	if user == nil {
+		return !hasUsers()
+	}
+	var count int64
+	db.Model(&Subscription{}).Where("user_id = ? AND channel_id = ?", user.ID, channelID).Count(&count)
+	return count > 0
+}
+
+// 节目不存在或所属频道不可访问时返回 false
+func canAccessEpisode(user *User, guid string) bool {
+	var episode Episode
+	if db.Select("guid", "channel_id").Where("guid = ?", guid).Limit(1).Find(&episode).RowsAffected == 0 {
+		return false
+	}
+	return canAccessChannel(user, episode.ChannelID)
+}
+
+func subscribe(userID uint, channelID string) error {
+	return db.Where(Subscription{UserID: userID, ChannelID: channelID}).
+		FirstOrCreate(&Subscription{UserID: userID, ChannelID: channelID}).Error
+}
+
+func unsubscribe(userID uint, channelID string) error {
+	return db.Where("user_id = ? AND channel_id = ?", userID, channelID).Delete(&Subscription{}).Error
+}
+
+// 频道是否还有其他用户订阅
+func hasOtherSubscribers(userID uint, channelID string) bool {
+	var count int64
+	db.Model(&Subscription{}).Where("channel_id = ? AND user_id <> ?", channelID, userID).Count(&count)
+	return count > 0
+}
+
+// 第一个注册的用户接管已有频道和摘要，保持升级前的数据可见。在注册的事务中执行
+func adoptLegacyData(tx *gorm.DB, user *User) error {
+	var channelIDs []string
+	if err := tx.Model(&Channel{}).Pluck("id", &channelIDs).Error; err != nil {
+		return err
+	}
+	for _, id := range channelIDs {
+		err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&Subscription{UserID: user.ID, ChannelID: id}).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	// 匿名使用时的播放状态、进度和播放列表归入该用户
+	updates := []*gorm.DB{
+		tx.Model(&UserEpisode{}).Where("user_id = ?", 0),
+		tx.Model(&PlaybackState{}).Where("user_id = ?", 0),
+		tx.Model(&Playlist{}).Where("user_id = ?", 0),
+		tx.Model(&EpisodeTag{}).Where("user_id = ? AND source <> ?", 0, TagSourceFeed),
+		tx.Model(&TagSuggestion{}).Where("user_id = ?", 0),
+	}
+	for _, query := range updates {
+		if err := query.Update("user_id", user.ID).Error; err != nil {
+			return err
+		}
+	}
+
+	var episodes []Episode
+	if err := tx.Select("guid", "summary").Where("summary <> ''").Find(&episodes).Error; err != nil {
+		return err
+	}
+	for _, ep := range episodes {
+		ue := UserEpisode{UserID: user.ID, EpisodeGUID: ep.GUID}
+		tx.Where("user_id = ? AND episode_guid = ?", user.ID, ep.GUID).Limit(1).Find(&ue)
+		ue.Summary = ep.Summary
+		if err := tx.Save(&ue).Error; err != nil {
+			return err
+		}
+	}
+	log.Printf("👤 User %s adopted %d channels and %d summaries", user.Username, len(channelIDs), len(episodes))
+	return nil
+}
+
+// 读取或创建用户的节目数据
+func getUserEpisode(userID uint, guid string) UserEpisode {
+	ue := UserEpisode{UserID: userID, EpisodeGUID: guid}
+	db.Where("user_id = ? AND episode_guid = ?", userID, guid).Limit(1).Find(&ue)
+	return ue
+}
+
+func saveUserEpisode(ue *UserEpisode) error {
+	return db.Save(ue).Error
+}
+
+// 用当前用户的个人数据覆盖节目中的标签、摘要和播放状态，匿名用户不覆盖摘要
+func applyUserEpisodeData(user *User, episodes []Episode) {
+	if len(episodes) == 0 {
+		return
+	}
+	var userID uint
+	if user != nil {
+		userID = user.ID
+	}
+	applyEpisodeTags(userID, episodes)
+	guids := make([]string, len(episodes))
+	for i, ep := range episodes {
+		guids[i] = ep.GUID
+	}
+	var rows []UserEpisode
+	db.Where("user_id = ? AND episode_guid IN ?", userID, guids).Find(&rows)
+	byGUID := make(map[string]*UserEpisode, len(rows))
+	for i := range rows {
+		byGUID[rows[i].EpisodeGUID] = &rows[i]
+	}
+
+	for i := range episodes {
+		ue := byGUID[episodes[i].GUID]
+		if user != nil {
+			// 摘要按用户隔离
+			episodes[i].Summary = ""
+			if ue != nil {
+				episodes[i].Summary = ue.Summary
+			}
+		}
+		if ue == nil {
+			continue
+		}
+		episodes[i].Played = ue.Played
+		episodes[i].PlayedAt = ue.PlayedAt
+		episodes[i].Starred = ue.Starred
+		episodes[i].Archived = ue.Archived
+	}
+}
+
+// 账号 API：/api/auth/...
+func authRouter(w http.ResponseWriter, r *http.Request) {
+	enableCors(&w)
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) < 3 {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch parts[2] {
+	case "register":
+		registerHandler(w, r)
+	case "login":
+		loginHandler(w, r)
+	case "logout":
+		logoutHandler(w, r)
+	case "me":
+		meHandler(w, r)
+	case "tokens":
+		if len(parts) == 4 {
+			deleteTokenHandler(w, r, parts[3])
+			return
+		}
+		tokensHandler(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+type credentials struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+func decodeCredentials(r *http.Request) (*credentials, error) {
+	var req credentials
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, err
+	}
+	req.Username = strings.ToLower(strings.TrimSpace(req.Username))
+	if req.Username == "" || req.Password == "" {
+		return nil, fmt.Errorf("username and password are required")
+	}
+	return &req, nil
+}
+
+// 登录成功后返回会话 token
+func writeSession(w http.ResponseWriter, status int, user *User) {
+	token, record, err := issueToken(user.ID, "session", sessionTokenTTL)
+	if err != nil {
+		http.Error(w, "Failed to create token", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":    true,
+		"user":       user,
+		"token":      token,
+		"expires_at": record.ExpiresAt,
+	})
+}
+
+func registerHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	req, err := decodeCredentials(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(req.Username) > 64 {
+		http.Error(w, "username is too long", http.StatusBadRequest)
+		return
+	}
+	if len(req.Password) < minPasswordLen {
+		http.Error(w, fmt.Sprintf("password must be at least %d characters", minPasswordLen), http.StatusBadRequest)
+		return
+	}
+
+	var existing User
+	if db.Where("username = ?", req.Username).Limit(1).Find(&existing).RowsAffected > 0 {
+		http.Error(w, "Username already taken", http.StatusConflict)
+		return
+	}
+	hash, err := hashPassword(req.Password)
+	if err != nil {
+		http.Error(w, "Failed to hash password", http.StatusInternalServerError)
+		return
+	}
+
+	// 插入后 id 最小的用户接管旧数据；锁定读取等待并发的注册提交，保证只有一个用户接管
+	user := &User{Username: req.Username, PasswordHash: hash}
+	created := false
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		created = true
+		var first User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Order("id").Limit(1).Find(&first).Error; err != nil {
+			return err
+		}
+		if first.ID != user.ID {
+			return nil
+		}
+		return adoptLegacyData(tx, user)
+	})
+	if err != nil && !created {
+		http.Error(w, "Username already taken", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Printf("❌ Failed to register user %s: %v", user.Username, err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("👤 Registered user: %s", user.Username)
+
+	writeSession(w, http.StatusCreated, user)
+}
+
+func loginHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	req, err := decodeCredentials(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var user User
+	if db.Where("username = ?", req.Username).Limit(1).Find(&user).RowsAffected == 0 ||
+		!verifyPassword(req.Password, user.PasswordHash) {
+		http.Error(w, "Invalid username or password", http.StatusUnauthorized)
+		return
+	}
+	writeSession(w, http.StatusOK, &user)
+}
+
+func logoutHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	token := currentToken(r)
+	if token == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	db.Delete(&AuthToken{}, token.ID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]bool{"success": true})
+}
+
+func meHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	user := currentUser(r)
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var subscriptions int64
+	db.Model(&Subscription{}).Where("user_id = ?", user.ID).Count(&subscriptions)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":       true,
+		"user":          user,
+		"subscriptions": subscriptions,
+	})
+}
+
+// 列出或创建 API token
+func tokensHandler(w http.ResponseWriter, r *http.Request) {
+	user := currentUser(r)
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		tokens := []AuthToken{}
+		db.Where("user_id = ?", user.ID).Order("id").Find(&tokens)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"tokens":  tokens,
+		})
+	case "POST":
+		var req struct {
+			Name string `json:"name"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		name := strings.TrimSpace(req.Name)
+		if name == "" {
+			name = "api"
+		}
+		token, record, err := issueToken(user.ID, name, 0)
+		if err != nil {
+			http.Error(w, "Failed to create token", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"token":   token,
+			"info":    record,
+		})
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func deleteTokenHandler(w http.ResponseWriter, r *http.Request, idStr string) {
+	if r.Method != "DELETE" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	user := currentUser(r)
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	id, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid token id", http.StatusBadRequest)
+		return
+	}
+	result := db.Where("id = ? AND user_id = ?", id, user.ID).Delete(&AuthToken{})
+	if result.RowsAffected == 0 {
+		http.Error(w, "Token not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]bool{"success": true})
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 保留最新 N 期时最多检查的节目数量
+const autoDownloadScanLimit = 200
+
+// 频道自动下载规则，所有条件同时满足才会下载
+type AutoDownloadRule struct {
+	Enabled    bool   `json:"enabled"`
+	KeepLatest int    `json:"keep_latest"` // 只保留最新 N 期，0 表示只下载之后新增的节目
+	Keyword    string `json:"keyword"`     // 标题关键词，逗号分隔，匹配任意一个即可
+	Tag        string `json:"tag"`
+	MaxMinutes int    `json:"max_minutes"` // 时长上限，0 表示不限制
+}
+
+func (rule *AutoDownloadRule) validate() error {
+	if rule.KeepLatest < 0 {
+		return fmt.Errorf("keep_latest must not be negative")
+	}
+	if rule.MaxMinutes < 0 {
+		return fmt.Errorf("max_minutes must not be negative")
+	}
+	rule.Keyword = strings.TrimSpace(rule.Keyword)
+	rule.Tag = strings.TrimSpace(rule.Tag)
+	return nil
+}
+
+// 对应 Channel 中 auto_download_ 前缀的列
+func (rule AutoDownloadRule) columns() map[string]interface{} {
+	return map[string]interface{}{
+		"auto_download_enabled":     rule.Enabled,
+		"auto_download_keep_latest": rule.KeepLatest,
+		"auto_download_keyword":     rule.Keyword,
+		"auto_download_tag":         rule.Tag,
+		"auto_download_max_minutes": rule.MaxMinutes,
+	}
+}
+
+func (rule AutoDownloadRule) matches(ep *Episode) bool {
+	if ep.AudioURL == "" || ep.RemovedAt != nil {
+		return false
+	}
+	if rule.Keyword != "" {
+		title := strings.ToLower(ep.Title)
+		matched := false
+		for _, kw := range strings.Split(rule.Keyword, ",") {
+			if kw = strings.ToLower(strings.TrimSpace(kw)); kw != "" && strings.Contains(title, kw) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if rule.Tag != "" {
+		matched := false
+		for _, t := range strings.Split(ep.Tags, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), rule.Tag) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	// 时长未知时不过滤
+	if rule.MaxMinutes > 0 && ep.DurationSeconds > rule.MaxMinutes*60 {
+		return false
+	}
+	return true
+}
+
+// 下载任务
+type DownloadTask struct {
+	GUID     string
+	AudioURL string
+	Title    string
+	Auto     bool // 由自动下载规则触发，超出保留数量时会被清理
+	AddedAt  time.Time
+}
+
+// 下载队列
+type DownloadQueue struct {
+	tasks []DownloadTask
+	mu    sync.Mutex
+}
+
+var downloadQueue *DownloadQueue
+
+// 初始化下载队列
+func initDownloadQueue() {
+	downloadQueue = &DownloadQueue{tasks: make([]DownloadTask, 0)}
+	log.Printf("📥 Download queue initialized")
+
+	go downloadWorker()
+}
+
+// 添加任务到下载队列
+func (q *DownloadQueue) AddTask(task DownloadTask) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, t := range q.tasks {
+		if t.GUID == task.GUID {
+			return
+		}
+	}
+	task.AddedAt = time.Now()
+	q.tasks = append(q.tasks, task)
+	log.Printf("➕ Added to download queue: %s (Queue size: %d)", task.Title, len(q.tasks))
+}
+
+// 获取下一个下载任务
+func (q *DownloadQueue) GetNextTask() *DownloadTask {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.tasks) == 0 {
+		return nil
+	}
+	task := q.tasks[0]
+	q.tasks = q.tasks[1:]
+	return &task
+}
+
+// 后台下载处理器
+func downloadWorker() {
+	for {
+		task := downloadQueue.GetNextTask()
+		if task == nil {
+			time.Sleep(5 * time.Second)
+			continue
+		}
+
+		var episode Episode
+		if db.Select("guid", "local_audio_path").Where("guid = ?", task.GUID).Limit(1).Find(&episode).RowsAffected == 0 {
+			continue
+		}
+		if episode.LocalAudioPath != "" && fileExists(episode.LocalAudioPath) {
+			continue
+		}
+
+		log.Printf("📥 Downloading: %s", task.Title)
+		localPath, err := downloadAudio(task.AudioURL, task.GUID)
+		if err != nil {
+			log.Printf("❌ Failed to download %s: %v", task.Title, err)
+			continue
+		}
+		db.Model(&Episode{}).Where("guid = ?", task.GUID).Updates(map[string]interface{}{
+			"local_audio_path": localPath,
+			"auto_downloaded":  task.Auto,
+		})
+	}
+}
+
+// 按频道规则安排自动下载。newGUIDs 为本次刷新新增的节目，
+// 未设置 keep_latest 时只下载这些节目
+func applyAutoDownloadRule(channel *Channel, newGUIDs []string) {
+	rule := channel.AutoDownload
+	if !rule.Enabled {
+		return
+	}
+
+	var episodes []Episode
+	query := db.Select("guid", "title", "audio_url", "duration_seconds", "local_audio_path", "auto_downloaded", "removed_at").
+		Where("channel_id = ? AND removed_at IS NULL", channel.ID)
+	if rule.KeepLatest > 0 {
+		query.Order("pub_date desc").Limit(autoDownloadScanLimit).Find(&episodes)
+	} else if len(newGUIDs) > 0 {
+		query.Where("guid IN ?", newGUIDs).Order("pub_date desc").Find(&episodes)
+	}
+	// 标签规则匹配 feed 分类和任意用户添加的标签
+	if rule.Tag != "" && len(episodes) > 0 {
+		guids := make([]string, len(episodes))
+		for i, ep := range episodes {
+			guids[i] = ep.GUID
+		}
+		names := map[string][]string{}
+		for _, row := range loadEpisodeTags(guids, nil) {
+			names[row.EpisodeGUID] = append(names[row.EpisodeGUID], row.Name)
+		}
+		for i := range episodes {
+			episodes[i].Tags = strings.Join(names[episodes[i].GUID], ",")
+		}
+	}
+
+	keep := map[string]bool{}
+	for i := range episodes {
+		ep := &episodes[i]
+		if rule.KeepLatest > 0 && len(keep) >= rule.KeepLatest {
+			break
+		}
+		if !rule.matches(ep) {
+			continue
+		}
+		keep[ep.GUID] = true
+		if ep.LocalAudioPath == "" || !fileExists(ep.LocalAudioPath) {
+			downloadQueue.AddTask(DownloadTask{GUID: ep.GUID, AudioURL: ep.AudioURL, Title: ep.Title, Auto: true})
+		}
+	}
+
+	if rule.KeepLatest > 0 {
+		pruneAutoDownloads(channel.ID, keep)
+	}
+}
+
+// 删除超出保留范围的自动下载文件，手动下载的文件不受影响
+func pruneAutoDownloads(channelID string, keep map[string]bool) {
+	var downloaded []Episode
+	db.Select("guid", "title", "local_audio_path").
+		Where("channel_id = ? AND auto_downloaded = ? AND local_audio_path <> ''", channelID, true).
+		Find(&downloaded)
+	for _, ep := range downloaded {
+		if keep[ep.GUID] {
+			continue
+		}
+		removeCachedAudio(ep.LocalAudioPath)
+		db.Model(&Episode{}).Where("guid = ?", ep.GUID).Updates(map[string]interface{}{
+			"local_audio_path": "",
+			"auto_downloaded":  false,
+		})
+		log.Printf("🧹 Pruned auto-downloaded audio: %s", ep.Title)
+	}
+}
@@ -0,0 +1,389 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 标签建议状态
+const (
+	TagSuggestionPending  = "pending"
+	TagSuggestionAccepted = "accepted"
+	TagSuggestionRejected = "rejected"
+)
+
+const (
+	// 每次最多建议的标签数，以及其中词表外新标签的数量
+	maxTagSuggestions    = 5
+	maxNewTagSuggestions = 2
+	// 未配置词表时，取使用最多的已有标签作为词表
+	maxTagVocabulary = 200
+)
+
+// 设置 AUTO_TAG_ENABLED=true 后，字幕写入时自动生成标签建议
+var autoTagEnabled = getEnv("AUTO_TAG_ENABLED", "") == "true"
+
+// 逗号分隔的标签词表，LLM 优先从中选择
+var autoTagVocabulary = getEnv("AUTO_TAG_VOCABULARY", "")
+
+// 保护词表读取和建议写入，调用 LLM 时不持有
+var autoTagMu sync.Mutex
+
+// LLM 给出的标签建议，接受后成为 llm 来源的标签
+type TagSuggestion struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	EpisodeGUID string    `json:"episode_guid" gorm:"uniqueIndex:idx_tag_suggestion"`
+	UserID      uint      `json:"user_id" gorm:"uniqueIndex:idx_tag_suggestion;index"`
+	Slug        string    `json:"-" gorm:"uniqueIndex:idx_tag_suggestion"`
+	Name        string    `json:"name"`
+	IsNew       bool      `json:"is_new"` // 不在词表中的新标签
+	Status      string    `json:"status" gorm:"index"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// 标签建议所用的词表：feed 标签加上 userID 自己的标签，userID 为 nil 时只用 feed 标签，
+// 避免把一个用户的私有标签发给 LLM 或建议给其他用户
+func tagVocabulary(userID *uint) []string {
+	if names := splitTagNames(autoTagVocabulary); len(names) > 0 {
+		return names
+	}
+	var names []string
+	query := db.Model(&EpisodeTag{}).Joins("JOIN tags ON tags.id = episode_tags.tag_id")
+	if userID != nil {
+		query = visibleEpisodeTags(query, *userID)
+	} else {
+		query = query.Where("episode_tags.source = ?", TagSourceFeed)
+	}
+	query.Group("tags.id, tags.name").Order("COUNT(*) DESC").Limit(maxTagVocabulary).Pluck("tags.name", &names)
+	return names
+}
+
+// 生成标签建议的提示词，要求 LLM 只输出 JSON
+func buildTagPrompt(title, content string, vocabulary []string) string {
+	var b strings.Builder
+	b.WriteString("你是一个播客内容分类助手。请根据节目标题和内容，为节目选择最贴切的主题标签。\n")
+	fmt.Fprintf(&b, "要求：1. 总共不超过 %d 个标签；2. 优先从候选词表中选择，放入 tags；", maxTagSuggestions)
+	fmt.Fprintf(&b, "3. 词表中没有合适的标签时，可以在 new_tags 中提出不超过 %d 个新标签，每个标签简短；", maxNewTagSuggestions)
+	b.WriteString("4. 只输出 JSON，格式为 {\"tags\": [], \"new_tags\": []}，不要输出其他内容。\n\n")
+	if len(vocabulary) > 0 {
+		b.WriteString("候选词表：" + strings.Join(vocabulary, ", ") + "\n\n")
+	}
+	b.WriteString("节目标题：" + title + "\n\n内容：\n" + content)
+	return b.String()
+}
+
+// 解析 LLM 的回复，词表中的标签统一为词表中的写法，其余视为新标签
+func parseTagSuggestions(reply string, vocabulary []string) (existing, proposed []string, err error) {
+	start, end := strings.Index(reply, "{"), strings.LastIndex(reply, "}")
+	if start < 0 || end < start {
+		return nil, nil, fmt.Errorf("no JSON object in LLM reply")
+	}
+	var parsed struct {
+		Tags    []string `json:"tags"`
+		NewTags []string `json:"new_tags"`
+	}
+	if err := json.Unmarshal([]byte(reply[start:end+1]), &parsed); err != nil {
+		return nil, nil, fmt.Errorf("invalid LLM reply: %v", err)
+	}
+
+	canonical := make(map[string]string, len(vocabulary))
+	for _, name := range vocabulary {
+		canonical[strings.ToLower(name)] = name
+	}
+	for _, name := range uniqueTagNames(append(parsed.Tags, parsed.NewTags...)) {
+		if len(existing)+len(proposed) >= maxTagSuggestions {
+			break
+		}
+		if known, ok := canonical[strings.ToLower(name)]; ok {
+			existing = append(existing, known)
+		} else if len(proposed) < maxNewTagSuggestions {
+			proposed = append(proposed, name)
+		}
+	}
+	return existing, proposed, nil
+}
+
+// LLM 请求参数，留空时使用环境变量
+type llmOptions struct {
+	APIKey  string `json:"apiKey"`
+	APIBase string `json:"apiBase"`
+	Model   string `json:"model"`
+}
+
+// 为节目生成标签建议并保存给指定用户，已建议过或已有的标签不会重复出现
+func suggestEpisodeTags(guid string, userIDs []uint, opts llmOptions) ([]TagSuggestion, error) {
+	var episode Episode
+	if db.Select("guid", "title", "summary", "srt_content", "transcript_text").Where("guid = ?", guid).Limit(1).Find(&episode).RowsAffected == 0 {
+		return nil, fmt.Errorf("episode not found")
+	}
+	content := episode.Summary
+	if len(userIDs) == 1 && userIDs[0] != 0 {
+		if ue := getUserEpisode(userIDs[0], guid); ue.Summary != "" {
+			content = ue.Summary
+		}
+	}
+	if transcript := episodeTranscriptText(episode); transcript != "" {
+		content = strings.TrimSpace(content + "\n\n" + transcript)
+	}
+	if content == "" {
+		return nil, fmt.Errorf("episode has no transcript or summary")
+	}
+	if len(content) > maxSummaryContentLength {
+		content = strings.ToValidUTF8(content[:maxSummaryContentLength], "")
+	}
+
+	// 同时建议给多个用户时只用 feed 标签作词表
+	var vocabularyUser *uint
+	if len(userIDs) == 1 {
+		vocabularyUser = &userIDs[0]
+	}
+	autoTagMu.Lock()
+	vocabulary := tagVocabulary(vocabularyUser)
+	autoTagMu.Unlock()
+	reply, err := callLLM(buildTagPrompt(episode.Title, content, vocabulary), opts.APIKey, opts.APIBase, opts.Model)
+	if err != nil {
+		return nil, err
+	}
+	existing, proposed, err := parseTagSuggestions(reply, vocabulary)
+	if err != nil {
+		return nil, err
+	}
+
+	names := append(append([]string{}, existing...), proposed...)
+	created := []TagSuggestion{}
+	autoTagMu.Lock()
+	defer autoTagMu.Unlock()
+	for _, userID := range userIDs {
+		tagged := map[string]bool{}
+		for _, row := range loadEpisodeTags([]string{guid}, &userID) {
+			tagged[strings.ToLower(row.Name)] = true
+		}
+		for i, name := range names {
+			if tagged[strings.ToLower(name)] {
+				continue
+			}
+			suggestion := TagSuggestion{
+				EpisodeGUID: guid,
+				UserID:      userID,
+				Slug:        strings.ToLower(name),
+				Name:        name,
+				IsNew:       i >= len(existing),
+				Status:      TagSuggestionPending,
+			}
+			result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&suggestion)
+			if result.Error != nil {
+				return nil, result.Error
+			}
+			if result.RowsAffected > 0 {
+				created = append(created, suggestion)
+			}
+		}
+	}
+	return created, nil
+}
+
+// 自动生成标签建议，建议给频道的所有订阅者，没有用户时给匿名用户
+func autoSuggestEpisodeTags(guid string) {
+	if !autoTagEnabled {
+		return
+	}
+	go func() {
+		var userIDs []uint
+		db.Model(&Subscription{}).Where("channel_id IN (?)",
+			db.Model(&Episode{}).Select("channel_id").Where("guid = ?", guid)).Pluck("user_id", &userIDs)
+		if len(userIDs) == 0 {
+			userIDs = []uint{0}
+		}
+		created, err := suggestEpisodeTags(guid, userIDs, llmOptions{})
+		if err != nil {
+			log.Printf("❌ Auto-tagging failed for %s: %v", guid, err)
+			return
+		}
+		log.Printf("🏷️ Auto-tagging suggested %d tag(s) for %s", len(created), guid)
+	}()
+}
+
+var errSuggestionNotPending = errors.New("suggestion is not pending")
+
+// 只更新仍在等待处理的建议，已被接受或拒绝时返回 errSuggestionNotPending
+func setTagSuggestionStatus(tx *gorm.DB, suggestion *TagSuggestion, status string) error {
+	result := tx.Model(&TagSuggestion{}).Where("id = ? AND status = ?", suggestion.ID, TagSuggestionPending).Update("status", status)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errSuggestionNotPending
+	}
+	suggestion.Status = status
+	return nil
+}
+
+// 接受建议：添加为 llm 来源的标签
+func acceptTagSuggestion(suggestion *TagSuggestion) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := setTagSuggestionStatus(tx, suggestion, TagSuggestionAccepted); err != nil {
+			return err
+		}
+		tag, err := findOrCreateTag(tx, suggestion.Name)
+		if err != nil {
+			return err
+		}
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&EpisodeTag{
+			EpisodeGUID: suggestion.EpisodeGUID, TagID: tag.ID, UserID: suggestion.UserID, Source: TagSourceLLM,
+		}).Error
+	})
+}
+
+// 合并重复节目时迁移标签建议，目标节目已有同名建议时删除旧的
+func reassignTagSuggestions(tx *gorm.DB, fromGUID, toGUID string) error {
+	var rows []TagSuggestion
+	tx.Where("episode_guid = ?", fromGUID).Find(&rows)
+	for _, row := range rows {
+		var count int64
+		tx.Model(&TagSuggestion{}).Where("episode_guid = ? AND user_id = ? AND slug = ?", toGUID, row.UserID, row.Slug).Count(&count)
+		if count > 0 {
+			if err := tx.Delete(&TagSuggestion{}, row.ID).Error; err != nil {
+				return err
+			}
+		} else if err := tx.Model(&TagSuggestion{}).Where("id = ?", row.ID).Update("episode_guid", toGUID).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 标签建议 API：/api/episodes/{guid}/tag-suggestions[/{id}/accept|reject]
+func episodeTagSuggestionsHandler(w http.ResponseWriter, r *http.Request, guid string, rest []string) {
+	userID := currentUserID(r)
+
+	if len(rest) == 2 {
+		id, err := strconv.ParseUint(rest[0], 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid suggestion id", http.StatusBadRequest)
+			return
+		}
+		if r.Method != "POST" {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var suggestion TagSuggestion
+		if db.Where("id = ? AND episode_guid = ? AND user_id = ?", id, guid, userID).Limit(1).Find(&suggestion).RowsAffected == 0 {
+			http.Error(w, "Suggestion not found", http.StatusNotFound)
+			return
+		}
+		switch rest[1] {
+		case "accept":
+			err = acceptTagSuggestion(&suggestion)
+		case "reject":
+			err = setTagSuggestionStatus(db, &suggestion, TagSuggestionRejected)
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		if errors.Is(err, errSuggestionNotPending) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success":    true,
+			"suggestion": suggestion,
+		})
+		return
+	}
+	if len(rest) != 0 {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		query := db.Where("episode_guid = ? AND user_id = ?", guid, userID)
+		if status := r.URL.Query().Get("status"); status != "" {
+			query = query.Where("status = ?", status)
+		}
+		suggestions := []TagSuggestion{}
+		query.Order("id").Find(&suggestions)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success":     true,
+			"suggestions": suggestions,
+		})
+	case "POST":
+		var opts llmOptions
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&opts); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		var count int64
+		db.Model(&Episode{}).Where("guid = ?", guid).Count(&count)
+		if count == 0 {
+			http.Error(w, "Episode not found", http.StatusNotFound)
+			return
+		}
+		created, err := suggestEpisodeTags(guid, []uint{userID}, opts)
+		if err != nil {
+			log.Printf("❌ Tag suggestion failed for %s: %v", guid, err)
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"success": false,
+				"message": fmt.Sprintf("生成标签建议失败: %v", err),
+			})
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success":     true,
+			"suggestions": created,
+		})
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// 当前用户所有待处理的标签建议：/api/tag-suggestions?limit=
+func pendingTagSuggestionsHandler(w http.ResponseWriter, r *http.Request) {
+	enableCors(&w)
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	limit := 100
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			http.Error(w, "invalid limit: "+v, http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+	suggestions := []TagSuggestion{}
+	db.Where("user_id = ? AND status = ?", currentUserID(r), TagSuggestionPending).
+		Order("id desc").Limit(limit).Find(&suggestions)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":     true,
+		"suggestions": suggestions,
+	})
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// 转录任务优先级，用户手动提交的任务优先处理
+const (
+	TranscriptionPriorityAuto = 0
+	TranscriptionPriorityUser = 10
+)
+
+// 频道自动转录策略，latest_n 和 since 同时设置时需同时满足
+type AutoTranscribePolicy struct {
+	Enabled bool       `json:"enabled"`
+	LatestN int        `json:"latest_n"` // 只转录最新 N 期，0 表示不限制
+	Since   *time.Time `json:"since"`    // 只转录该时间之后发布的节目
+}
+
+func (p *AutoTranscribePolicy) validate() error {
+	if p.LatestN < 0 {
+		return fmt.Errorf("latest_n must not be negative")
+	}
+	return nil
+}
+
+// 对应 Channel 中 auto_transcribe_ 前缀的列
+func (p AutoTranscribePolicy) columns() map[string]interface{} {
+	return map[string]interface{}{
+		"auto_transcribe_enabled":  p.Enabled,
+		"auto_transcribe_latest_n": p.LatestN,
+		"auto_transcribe_since":    p.Since,
+	}
+}
+
+// 按频道策略将节目加入转录队列。newGUIDs 为 nil 时按策略检查已有节目
+// （首次导入或修改策略时），否则只考虑本次刷新新增的节目
+func applyAutoTranscribePolicy(channel *Channel, newGUIDs []string) {
+	policy := channel.AutoTranscribe
+	if !policy.Enabled {
+		return
+	}
+	// 没有任何限制时只处理新增节目，避免把整个历史加入队列
+	if newGUIDs == nil && policy.LatestN == 0 && policy.Since == nil {
+		return
+	}
+	if newGUIDs != nil && len(newGUIDs) == 0 {
+		return
+	}
+
+	query := db.Select("guid", "title", "audio_url", "local_audio_path", "srt_content", "transcript_stale").
+		Where("channel_id = ? AND removed_at IS NULL", channel.ID)
+	if policy.Since != nil {
+		query = query.Where("pub_date >= ?", *policy.Since)
+	}
+	query = query.Order("pub_date desc")
+	if policy.LatestN > 0 {
+		query = query.Limit(policy.LatestN)
+	} else if newGUIDs != nil {
+		query = query.Where("guid IN ?", newGUIDs)
+	}
+	var episodes []Episode
+	query.Find(&episodes)
+
+	var isNew map[string]bool
+	if newGUIDs != nil {
+		isNew = make(map[string]bool, len(newGUIDs))
+		for _, guid := range newGUIDs {
+			isNew[guid] = true
+		}
+	}
+
+	queued := 0
+	for _, ep := range episodes {
+		if isNew != nil && !isNew[ep.GUID] {
+			continue
+		}
+		if ep.AudioURL == "" || (ep.SrtContent != "" && !ep.TranscriptStale) {
+			continue
+		}
+		transcriptionQueue.AddTask(TranscriptionTask{
+			GUID:      ep.GUID,
+			AudioURL:  ep.AudioURL,
+			LocalPath: ep.LocalAudioPath,
+			Title:     ep.Title,
+			Priority:  TranscriptionPriorityAuto,
+			AddedAt:   time.Now(),
+		})
+		queued++
+	}
+	if queued > 0 {
+		log.Printf("🤖 Auto-transcription queued %d episode(s) for %s", queued, channel.Name)
+	}
+}
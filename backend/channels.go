@@ -0,0 +1,474 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/mmcdole/gofeed"
+)
+
+// 拉取并解析 RSS，用于添加/修改频道时校验地址
+func fetchAndValidateFeed(feedURL string) (*gofeed.Feed, error) {
+	parsed, err := url.Parse(feedURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, fmt.Errorf("invalid feed URL: %s", feedURL)
+	}
+
+	fp := gofeed.NewParser()
+	fp.Client = &http.Client{Timeout: 30 * time.Second}
+	feed, err := fp.ParseURL(feedURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse feed: %v", err)
+	}
+	if feed.Title == "" && len(feed.Items) == 0 {
+		return nil, fmt.Errorf("feed has no title and no items")
+	}
+	return feed, nil
+}
+
+// 从 feed 中提取作者
+func feedAuthor(feed *gofeed.Feed) string {
+	if feed.ITunesExt != nil && feed.ITunesExt.Author != "" {
+		return feed.ITunesExt.Author
+	}
+	for _, a := range feed.Authors {
+		if a != nil && a.Name != "" {
+			return a.Name
+		}
+	}
+	return ""
+}
+
+// 将标题转换为 URL 友好的 slug，非 ASCII 字符会被丢弃
+func slugify(s string) string {
+	var b strings.Builder
+	lastDash := true
+	for _, r := range strings.ToLower(s) {
+		if r <= unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			b.WriteRune(r)
+			lastDash = false
+		} else if !lastDash {
+			b.WriteByte('-')
+			lastDash = true
+		}
+	}
+	return strings.Trim(b.String(), "-")
+}
+
+// 根据标题和 RSS 地址生成稳定的频道 ID：
+// 优先使用标题 slug，冲突或为空时追加 RSS 地址的哈希
+func generateChannelID(title, feedURL string) string {
+	sum := sha1.Sum([]byte(strings.TrimSpace(feedURL)))
+	hash := hex.EncodeToString(sum[:])[:8]
+
+	slug := slugify(title)
+	if len(slug) > 48 {
+		slug = strings.Trim(slug[:48], "-")
+	}
+	if slug == "" {
+		return "feed-" + hash
+	}
+
+	var existing Channel
+	if db.Where("id = ?", slug).Limit(1).Find(&existing).RowsAffected == 0 {
+		return slug
+	}
+	return slug + "-" + hash
+}
+
+// 删除频道的节目及其缓存的音频文件
+func purgeChannelEpisodes(channelID string) (int64, int) {
+	var episodes []Episode
+	db.Select("guid", "local_audio_path").Where("channel_id = ?", channelID).Find(&episodes)
+
+	removedFiles := 0
+	for _, ep := range episodes {
+		if removeCachedAudio(ep.LocalAudioPath) {
+			removedFiles++
+		}
+	}
+
+	episodeGUIDs := db.Model(&Episode{}).Select("guid").Where("channel_id = ?", channelID)
+	db.Where("episode_guid IN (?)", episodeGUIDs).Delete(&UserEpisode{})
+	db.Where("episode_guid IN (?)", episodeGUIDs).Delete(&PlaybackState{})
+	db.Where("episode_guid IN (?)", episodeGUIDs).Delete(&PlaylistItem{})
+	db.Where("episode_guid IN (?)", episodeGUIDs).Delete(&EpisodeTag{})
+	db.Where("episode_guid IN (?)", episodeGUIDs).Delete(&TagSuggestion{})
+	db.Where("episode_guid IN (?)", episodeGUIDs).Delete(&TranscriptSegment{})
+	removeChannelFromSearch(channelID)
+	result := db.Where("channel_id = ?", channelID).Delete(&Episode{})
+	return result.RowsAffected, removedFiles
+}
+
+// 删除 media_cache 中的音频文件，只允许删除缓存目录内的文件
+func removeCachedAudio(localPath string) bool {
+	if localPath == "" {
+		return false
+	}
+	cacheDir, err := filepath.Abs("media_cache")
+	if err != nil {
+		return false
+	}
+	absPath, err := filepath.Abs(localPath)
+	if err != nil || filepath.Dir(absPath) != cacheDir {
+		log.Printf("⚠️ Refusing to delete file outside media cache: %s", localPath)
+		return false
+	}
+	if err := os.Remove(absPath); err != nil {
+		return false
+	}
+	log.Printf("🗑️ Deleted cached audio: %s", localPath)
+	return true
+}
+
+// 添加频道
+func createChannelHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		RSS         string `json:"rss"`
+		ID          string `json:"id"`
+		Name        string `json:"name"`
+		Author      string `json:"author"`
+		Description string `json:"description"`
+		Category    string `json:"category"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	req.RSS = strings.TrimSpace(req.RSS)
+	if req.RSS == "" {
+		http.Error(w, "RSS URL is required", http.StatusBadRequest)
+		return
+	}
+
+	// 同一个 RSS 只允许添加一次，登录用户添加已有频道时直接订阅
+	user := currentUser(r)
+	var existing Channel
+	if db.Where("rss = ?", req.RSS).Limit(1).Find(&existing).RowsAffected > 0 {
+		if user != nil && !canAccessChannel(user, existing.ID) {
+			if err := subscribe(user.ID, existing.ID); err != nil {
+				http.Error(w, "Database error", http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"success":    true,
+				"subscribed": true,
+				"channel":    existing,
+			})
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"message": "Channel already exists",
+			"channel": existing,
+		})
+		return
+	}
+
+	feed, err := fetchAndValidateFeed(req.RSS)
+	if err != nil {
+		log.Printf("❌ Failed to validate feed %s: %v", req.RSS, err)
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	channel, err := createChannelFromFeed(req.RSS, feed, Channel{
+		ID:          req.ID,
+		Name:        req.Name,
+		Author:      req.Author,
+		Description: req.Description,
+		Category:    strings.TrimSpace(req.Category),
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if user != nil {
+		subscribe(user.ID, channel.ID)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"channel": channel,
+	})
+}
+
+// 根据已解析的 feed 创建频道，overrides 中的非空字段优先
+func createChannelFromFeed(feedURL string, feed *gofeed.Feed, overrides Channel) (*Channel, error) {
+	channel := Channel{
+		ID:          overrides.ID,
+		Name:        overrides.Name,
+		Author:      overrides.Author,
+		RSS:         feedURL,
+		Description: overrides.Description,
+		Category:    overrides.Category,
+	}
+	if channel.Name == "" {
+		channel.Name = strings.TrimSpace(feed.Title)
+	}
+	if channel.Author == "" {
+		channel.Author = feedAuthor(feed)
+	}
+	if channel.Description == "" {
+		channel.Description = strings.TrimSpace(feed.Description)
+	}
+	if channel.ID == "" {
+		channel.ID = generateChannelID(channel.Name, feedURL)
+	}
+	// 节目会在下方立即导入，调度器按正常间隔接管
+	next := nextRefreshTime(&channel)
+	channel.NextRefreshAt = &next
+
+	if err := db.Create(&channel).Error; err != nil {
+		return nil, fmt.Errorf("failed to create channel %s: %v", channel.ID, err)
+	}
+	log.Printf("➕ Added channel: %s (%s)", channel.Name, channel.ID)
+
+	// 后台导入节目，避免阻塞请求
+	go func(ch Channel) {
+		applyFeed(&ch, feed)
+		markChannelRefreshed(&ch)
+	}(channel)
+
+	return &channel, nil
+}
+
+// 修改频道。频道设置在订阅者之间共享，有其他订阅者时不允许修改
+func updateChannelHandler(w http.ResponseWriter, r *http.Request, channel *Channel) {
+	if user := currentUser(r); user != nil && hasOtherSubscribers(user.ID, channel.ID) {
+		http.Error(w, "Channel is shared with other users", http.StatusForbidden)
+		return
+	}
+
+	var req struct {
+		Name            *string `json:"name"`
+		Author          *string `json:"author"`
+		Description     *string `json:"description"`
+		Category        *string `json:"category"`
+		RSS             *string `json:"rss"`
+		RefreshInterval *int    `json:"refresh_interval"`
+		// 提供时整体替换自动下载规则
+		AutoDownload   *AutoDownloadRule     `json:"auto_download"`
+		AutoTranscribe *AutoTranscribePolicy `json:"auto_transcribe"`
+		Profile        *ChannelProfile       `json:"profile"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if req.Name != nil {
+		updates["name"] = strings.TrimSpace(*req.Name)
+	}
+	if req.Author != nil {
+		updates["author"] = strings.TrimSpace(*req.Author)
+	}
+	if req.Description != nil {
+		updates["description"] = *req.Description
+	}
+	if req.Category != nil {
+		updates["category"] = strings.TrimSpace(*req.Category)
+	}
+	if req.RefreshInterval != nil {
+		if *req.RefreshInterval < 0 {
+			http.Error(w, "refresh_interval must not be negative", http.StatusBadRequest)
+			return
+		}
+		channel.RefreshInterval = *req.RefreshInterval
+		updates["refresh_interval"] = *req.RefreshInterval
+		updates["next_refresh_at"] = nextRefreshTime(channel)
+	}
+	if req.AutoDownload != nil {
+		if err := req.AutoDownload.validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		for column, value := range req.AutoDownload.columns() {
+			updates[column] = value
+		}
+	}
+	if req.AutoTranscribe != nil {
+		if err := req.AutoTranscribe.validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		for column, value := range req.AutoTranscribe.columns() {
+			updates[column] = value
+		}
+	}
+	if req.Profile != nil {
+		if err := req.Profile.validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		for column, value := range req.Profile.columns() {
+			updates[column] = value
+		}
+	}
+	if req.RSS != nil && strings.TrimSpace(*req.RSS) != channel.RSS {
+		newRSS := strings.TrimSpace(*req.RSS)
+		var existing Channel
+		if db.Where("rss = ? AND id <> ?", newRSS, channel.ID).Limit(1).Find(&existing).RowsAffected > 0 {
+			http.Error(w, fmt.Sprintf("RSS already used by channel %s", existing.ID), http.StatusConflict)
+			return
+		}
+		if _, err := fetchAndValidateFeed(newRSS); err != nil {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		updates["rss"] = newRSS
+		// 新地址的校验值需要重新获取
+		updates["etag"] = ""
+		updates["last_modified"] = ""
+	}
+
+	oldRSS := channel.RSS
+	if len(updates) > 0 {
+		if err := db.Model(channel).Updates(updates).Error; err != nil {
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		if newRSS, ok := updates["rss"].(string); ok {
+			recordFeedMove(channel.ID, oldRSS, newRSS, FeedMoveManual)
+		}
+		log.Printf("✏️ Updated channel: %s", channel.ID)
+	}
+
+	db.First(channel, "id = ?", channel.ID)
+	if req.AutoDownload != nil {
+		go func(ch Channel) { applyAutoDownloadRule(&ch, nil) }(*channel)
+	}
+	if req.AutoTranscribe != nil {
+		go func(ch Channel) { applyAutoTranscribePolicy(&ch, nil) }(*channel)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"channel": channel,
+	})
+}
+
+// 删除频道，?purge=true 时同时删除节目和缓存音频。
+// 登录用户删除时取消自己的订阅，最后一个订阅者取消后才真正删除频道；
+// 还有其他订阅者时不能 purge
+func deleteChannelHandler(w http.ResponseWriter, r *http.Request, channel *Channel) {
+	purge := r.URL.Query().Get("purge") == "true"
+
+	if user := currentUser(r); user != nil {
+		others := hasOtherSubscribers(user.ID, channel.ID)
+		if others && purge {
+			http.Error(w, "Channel has other subscribers, cannot purge", http.StatusConflict)
+			return
+		}
+		if err := unsubscribe(user.ID, channel.ID); err != nil {
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		log.Printf("👋 User %s unsubscribed from %s", user.Username, channel.ID)
+		if others {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"success":      true,
+				"unsubscribed": true,
+			})
+			return
+		}
+	}
+
+	var deletedEpisodes int64
+	removedFiles := 0
+	if purge {
+		deletedEpisodes, removedFiles = purgeChannelEpisodes(channel.ID)
+	}
+
+	if err := db.Delete(&Channel{}, "id = ?", channel.ID).Error; err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	db.Where("channel_id = ?", channel.ID).Delete(&FeedFailure{})
+	db.Where("channel_id = ?", channel.ID).Delete(&FeedURLHistory{})
+	db.Where("channel_id = ?", channel.ID).Delete(&Subscription{})
+	log.Printf("🗑️ Deleted channel: %s (episodes: %d, files: %d)", channel.ID, deletedEpisodes, removedFiles)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":          true,
+		"deleted_episodes": deletedEpisodes,
+		"removed_files":    removedFiles,
+	})
+}
+
+// 频道子路由：/api/channels/{id}、/api/channels/{id}/episodes 和 /api/channels/{id}/health
+func channelRouter(w http.ResponseWriter, r *http.Request) {
+	enableCors(&w)
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) < 3 || parts[2] == "" {
+		http.Error(w, "Invalid path", http.StatusBadRequest)
+		return
+	}
+
+	if !canAccessChannel(currentUser(r), parts[2]) {
+		http.Error(w, "Channel not found", http.StatusNotFound)
+		return
+	}
+
+	if len(parts) >= 4 && parts[3] == "episodes" {
+		channelEpisodesHandler(w, r)
+		return
+	}
+	if len(parts) == 4 && parts[3] == "health" {
+		channelHealthHandler(w, r, parts[2])
+		return
+	}
+	if len(parts) == 4 && parts[3] == "feed-history" {
+		channelFeedHistoryHandler(w, r, parts[2])
+		return
+	}
+	if len(parts) == 4 && parts[3] == "mark-played" {
+		channelMarkPlayedHandler(w, r, parts[2])
+		return
+	}
+	if len(parts) > 3 {
+		http.NotFound(w, r)
+		return
+	}
+
+	var channel Channel
+	if result := db.First(&channel, "id = ?", parts[2]); result.Error != nil {
+		http.Error(w, "Channel not found", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(channel)
+	case "PATCH":
+		updateChannelHandler(w, r, &channel)
+	case "DELETE":
+		deleteChannelHandler(w, r, &channel)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
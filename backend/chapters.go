@@ -0,0 +1,190 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+	"gorm.io/gorm"
+)
+
+// 章节来源
+const (
+	ChapterSourceFeed = "feed"
+	ChapterSourceID3  = "id3"
+)
+
+// 节目章节
+type Chapter struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	EpisodeGUID string    `json:"episode_guid" gorm:"index"`
+	Position    int       `json:"position"`
+	StartTime   float64   `json:"start_time"` // 秒
+	EndTime     float64   `json:"end_time,omitempty"`
+	Title       string    `json:"title"`
+	URL         string    `json:"url,omitempty"`
+	ImageURL    string    `json:"image_url,omitempty"`
+	Source      string    `json:"source"` // feed / id3
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// 从 podcast:chapters 标签中取章节文件地址
+func findChaptersURL(item *gofeed.Item) string {
+	for _, e := range item.Extensions["podcast"]["chapters"] {
+		if url := e.Attrs["url"]; url != "" {
+			return url
+		}
+	}
+	return ""
+}
+
+// 替换节目的章节，feed 章节优先于 ID3 章节
+func replaceChapters(guid, source string, chapters []Chapter) error {
+	if source == ChapterSourceID3 {
+		var count int64
+		db.Model(&Chapter{}).Where("episode_guid = ? AND source = ?", guid, ChapterSourceFeed).Count(&count)
+		if count > 0 {
+			return nil
+		}
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("episode_guid = ?", guid).Delete(&Chapter{}).Error; err != nil {
+			return err
+		}
+		for i := range chapters {
+			chapters[i].EpisodeGUID = guid
+			chapters[i].Position = i
+			chapters[i].Source = source
+		}
+		if len(chapters) == 0 {
+			return nil
+		}
+		return tx.Create(&chapters).Error
+	})
+}
+
+// 下载并解析 Podcasting 2.0 JSON 章节
+func fetchFeedChapters(chaptersURL string) ([]Chapter, error) {
+	req, err := http.NewRequest("GET", chaptersURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", feedUserAgent)
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("chapters returned HTTP %d", resp.StatusCode)
+	}
+	return parseFeedChapters(io.LimitReader(resp.Body, 5<<20))
+}
+
+// 解析 Podcasting 2.0 JSON 章节
+func parseFeedChapters(r io.Reader) ([]Chapter, error) {
+	var doc struct {
+		Chapters []struct {
+			StartTime float64 `json:"startTime"`
+			EndTime   float64 `json:"endTime"`
+			Title     string  `json:"title"`
+			Img       string  `json:"img"`
+			URL       string  `json:"url"`
+			TOC       *bool   `json:"toc"`
+		} `json:"chapters"`
+	}
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid chapters JSON: %v", err)
+	}
+
+	var chapters []Chapter
+	for _, c := range doc.Chapters {
+		// toc=false 表示不对用户展示的章节
+		if c.TOC != nil && !*c.TOC {
+			continue
+		}
+		chapters = append(chapters, Chapter{
+			StartTime: c.StartTime,
+			EndTime:   c.EndTime,
+			Title:     strings.TrimSpace(c.Title),
+			URL:       c.URL,
+			ImageURL:  c.Img,
+		})
+	}
+	return chapters, nil
+}
+
+// 抓取 feed 章节，已有 feed 章节的节目会被跳过
+func ingestFeedChapters(guid, chaptersURL string) {
+	var count int64
+	db.Model(&Chapter{}).Where("episode_guid = ? AND source = ?", guid, ChapterSourceFeed).Count(&count)
+	if count > 0 {
+		return
+	}
+
+	chapters, err := fetchFeedChapters(chaptersURL)
+	if err != nil {
+		log.Printf("⚠️ Failed to fetch chapters for %s: %v", guid, err)
+		return
+	}
+	if err := replaceChapters(guid, ChapterSourceFeed, chapters); err != nil {
+		log.Printf("❌ Failed to save chapters for %s: %v", guid, err)
+		return
+	}
+	log.Printf("📑 Saved %d feed chapters for %s", len(chapters), guid)
+}
+
+// 从已下载的音频文件中读取 ID3 章节
+func importID3Chapters(guid, localPath string) {
+	id3Chapters, err := readID3Chapters(localPath)
+	if err != nil || len(id3Chapters) == 0 {
+		return
+	}
+
+	chapters := make([]Chapter, 0, len(id3Chapters))
+	for _, c := range id3Chapters {
+		chapters = append(chapters, Chapter{
+			StartTime: float64(c.StartMs) / 1000,
+			EndTime:   float64(c.EndMs) / 1000,
+			Title:     c.Title,
+			URL:       c.URL,
+		})
+	}
+	if err := replaceChapters(guid, ChapterSourceID3, chapters); err != nil {
+		log.Printf("❌ Failed to save ID3 chapters for %s: %v", guid, err)
+		return
+	}
+	log.Printf("📑 Read %d ID3 chapters from %s", len(chapters), localPath)
+}
+
+// 章节 API
+func episodeChaptersHandler(w http.ResponseWriter, r *http.Request, guid string) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var count int64
+	db.Model(&Episode{}).Where("guid = ?", guid).Count(&count)
+	if count == 0 {
+		http.Error(w, "Episode not found", http.StatusNotFound)
+		return
+	}
+
+	chapters := []Chapter{}
+	db.Where("episode_guid = ?", guid).Order("position").Find(&chapters)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":  true,
+		"chapters": chapters,
+	})
+}
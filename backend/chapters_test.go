@@ -0,0 +1,60 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseFeedChapters(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []Chapter
+		wantErr bool
+	}{
+		{
+			name: "chapters with optional fields",
+			input: `{"version":"1.2.0","chapters":[
+				{"startTime":0,"title":" Intro ","img":"https://example.com/a.jpg"},
+				{"startTime":61.5,"endTime":120,"title":"Main","url":"https://example.com"}]}`,
+			want: []Chapter{
+				{StartTime: 0, Title: "Intro", ImageURL: "https://example.com/a.jpg"},
+				{StartTime: 61.5, EndTime: 120, Title: "Main", URL: "https://example.com"},
+			},
+		},
+		{
+			name:  "toc false is hidden",
+			input: `{"chapters":[{"startTime":0,"title":"Shown","toc":true},{"startTime":5,"title":"Hidden","toc":false}]}`,
+			want:  []Chapter{{StartTime: 0, Title: "Shown"}},
+		},
+		{name: "no chapters", input: `{"version":"1.2.0"}`},
+		{name: "empty chapters", input: `{"chapters":[]}`},
+		{name: "empty input", input: ``, wantErr: true},
+		{name: "truncated", input: `{"chapters":[{"startTime":0,"tit`, wantErr: true},
+		{name: "not JSON", input: `<chapters/>`, wantErr: true},
+		{name: "wrong type", input: `{"chapters":[{"startTime":"0:00","title":"A"}]}`, wantErr: true},
+		{name: "chapters not an array", input: `{"chapters":{"startTime":0}}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseFeedChapters(strings.NewReader(tt.input))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("chapter %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
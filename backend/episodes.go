@@ -0,0 +1,309 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	defaultEpisodePageSize = 50
+	maxEpisodePageSize     = 200
+)
+
+// 节目列表的分页和筛选参数
+type episodeListOptions struct {
+	Limit         int
+	Ascending     bool
+	Cursor        *episodeCursor
+	Statuses      []string
+	HasSubtitles  *bool
+	HasLocalAudio *bool
+	Tag           string
+	Since         *time.Time
+	Until         *time.Time
+	// 默认不返回已下架的节目
+	IncludeRemoved bool
+	// 以下按当前用户的节目状态筛选，默认不返回已归档的节目
+	UserID          uint
+	Played          *bool
+	Starred         *bool
+	Archived        *bool
+	IncludeArchived bool
+}
+
+// 游标基于 (pub_date, guid)，与 idx_channel_pubdate 索引顺序一致
+type episodeCursor struct {
+	PubDate time.Time
+	GUID    string
+}
+
+func encodeEpisodeCursor(ep Episode) string {
+	raw := ep.PubDate.Format(time.RFC3339Nano) + "|" + ep.GUID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeEpisodeCursor(s string) (*episodeCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	parts := strings.SplitN(string(raw), "|", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	pubDate, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	return &episodeCursor{PubDate: pubDate.UTC(), GUID: parts[1]}, nil
+}
+
+// SQLite 按文本比较 pub_date，把旧数据中带时区偏移的值统一改写为 UTC
+func migratePubDatesToUTC() {
+	if db.Dialector.Name() != "sqlite" {
+		return
+	}
+	var episodes []Episode
+	if err := db.Select("guid", "pub_date").Where("pub_date NOT LIKE ?", "%+00:00").Find(&episodes).Error; err != nil {
+		log.Printf("❌ Failed to load pub dates for UTC migration: %v", err)
+		return
+	}
+	if len(episodes) == 0 {
+		return
+	}
+	for _, ep := range episodes {
+		if err := db.Model(&Episode{}).Where("guid = ?", ep.GUID).UpdateColumn("pub_date", ep.PubDate.UTC()).Error; err != nil {
+			log.Printf("❌ Failed to migrate pub date of %s: %v", ep.GUID, err)
+		}
+	}
+	log.Printf("🕒 Normalized pub dates of %d episode(s) to UTC", len(episodes))
+}
+
+// 解析布尔参数，空值返回 nil
+func parseOptionalBool(q url.Values, key string) (*bool, error) {
+	v := q.Get(key)
+	if v == "" {
+		return nil, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %s", key, v)
+	}
+	return &b, nil
+}
+
+// 解析日期参数，支持 RFC3339 和 YYYY-MM-DD
+func parseDateParam(q url.Values, key string) (*time.Time, error) {
+	return parseDateValue(key, q.Get(key))
+}
+
+func parseDateValue(key, v string) (*time.Time, error) {
+	if v == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		t = t.UTC()
+		return &t, nil
+	}
+	if t, err := time.Parse("2006-01-02", v); err == nil {
+		return &t, nil
+	}
+	return nil, fmt.Errorf("invalid %s: %s", key, v)
+}
+
+func parseEpisodeListOptions(q url.Values) (*episodeListOptions, error) {
+	opts := &episodeListOptions{Limit: defaultEpisodePageSize}
+	var err error
+
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid limit: %s", v)
+		}
+		if n > maxEpisodePageSize {
+			n = maxEpisodePageSize
+		}
+		opts.Limit = n
+	}
+
+	switch q.Get("order") {
+	case "", "desc":
+	case "asc":
+		opts.Ascending = true
+	default:
+		return nil, fmt.Errorf("invalid order: %s", q.Get("order"))
+	}
+
+	if v := q.Get("cursor"); v != "" {
+		if opts.Cursor, err = decodeEpisodeCursor(v); err != nil {
+			return nil, err
+		}
+	}
+
+	// transcription_status 支持逗号分隔多个值，none 表示未转录
+	if v := q.Get("transcription_status"); v != "" {
+		for _, s := range strings.Split(v, ",") {
+			s = strings.TrimSpace(s)
+			if s == "none" {
+				s = ""
+			}
+			opts.Statuses = append(opts.Statuses, s)
+		}
+	}
+
+	if opts.HasSubtitles, err = parseOptionalBool(q, "has_subtitles"); err != nil {
+		return nil, err
+	}
+	if opts.HasLocalAudio, err = parseOptionalBool(q, "has_local_audio"); err != nil {
+		return nil, err
+	}
+	opts.Tag = strings.TrimSpace(q.Get("tag"))
+	includeRemoved, err := parseOptionalBool(q, "include_removed")
+	if err != nil {
+		return nil, err
+	}
+	opts.IncludeRemoved = includeRemoved != nil && *includeRemoved
+
+	if opts.Played, err = parseOptionalBool(q, "played"); err != nil {
+		return nil, err
+	}
+	if opts.Starred, err = parseOptionalBool(q, "starred"); err != nil {
+		return nil, err
+	}
+	if opts.Archived, err = parseOptionalBool(q, "archived"); err != nil {
+		return nil, err
+	}
+	includeArchived, err := parseOptionalBool(q, "include_archived")
+	if err != nil {
+		return nil, err
+	}
+	opts.IncludeArchived = includeArchived != nil && *includeArchived
+
+	if opts.Since, err = parseDateParam(q, "since"); err != nil {
+		return nil, err
+	}
+	if opts.Until, err = parseDateParam(q, "until"); err != nil {
+		return nil, err
+	}
+	// 只给日期时包含当天
+	if opts.Until != nil && len(q.Get("until")) == len("2006-01-02") {
+		end := opts.Until.Add(24 * time.Hour)
+		opts.Until = &end
+	}
+	return opts, nil
+}
+
+// 将筛选、排序和游标条件应用到查询上
+func applyEpisodeListOptions(query *gorm.DB, opts *episodeListOptions) *gorm.DB {
+	if len(opts.Statuses) > 0 {
+		query = query.Where("transcription_status IN ?", opts.Statuses)
+	}
+	if opts.HasSubtitles != nil {
+		if *opts.HasSubtitles {
+			query = query.Where("srt_content <> ''")
+		} else {
+			query = query.Where("(srt_content = '' OR srt_content IS NULL)")
+		}
+	}
+	if opts.HasLocalAudio != nil {
+		if *opts.HasLocalAudio {
+			query = query.Where("local_audio_path <> ''")
+		} else {
+			query = query.Where("(local_audio_path = '' OR local_audio_path IS NULL)")
+		}
+	}
+	if opts.Tag != "" {
+		query = filterEpisodesByTag(query, opts.UserID, opts.Tag)
+	}
+	if !opts.IncludeRemoved {
+		query = query.Where("removed_at IS NULL")
+	}
+	if opts.Played != nil {
+		query = filterUserEpisodeState(query, opts.UserID, "played", *opts.Played)
+	}
+	if opts.Starred != nil {
+		query = filterUserEpisodeState(query, opts.UserID, "starred", *opts.Starred)
+	}
+	if opts.Archived != nil {
+		query = filterUserEpisodeState(query, opts.UserID, "archived", *opts.Archived)
+	} else if !opts.IncludeArchived {
+		query = filterUserEpisodeState(query, opts.UserID, "archived", false)
+	}
+	if opts.Since != nil {
+		query = query.Where("pub_date >= ?", *opts.Since)
+	}
+	if opts.Until != nil {
+		query = query.Where("pub_date < ?", *opts.Until)
+	}
+
+	if opts.Ascending {
+		if opts.Cursor != nil {
+			query = query.Where("(pub_date > ? OR (pub_date = ? AND guid > ?))",
+				opts.Cursor.PubDate, opts.Cursor.PubDate, opts.Cursor.GUID)
+		}
+		query = query.Order("pub_date asc, guid asc")
+	} else {
+		if opts.Cursor != nil {
+			query = query.Where("(pub_date < ? OR (pub_date = ? AND guid < ?))",
+				opts.Cursor.PubDate, opts.Cursor.PubDate, opts.Cursor.GUID)
+		}
+		query = query.Order("pub_date desc, guid desc")
+	}
+	return query.Limit(opts.Limit + 1)
+}
+
+// 节目子路由：/api/episodes/{guid}/...
+// GUID 可能包含 "/" 等字符，客户端需要对其做 URL 编码
+func episodeRouter(w http.ResponseWriter, r *http.Request) {
+	enableCors(&w)
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.EscapedPath(), "/"), "/")
+	if len(parts) < 4 || parts[2] == "" {
+		http.NotFound(w, r)
+		return
+	}
+	guid, err := url.PathUnescape(parts[2])
+	if err != nil {
+		http.Error(w, "Invalid GUID", http.StatusBadRequest)
+		return
+	}
+
+	// 节目所属频道不在当前用户的订阅中时按不存在处理
+	if !canAccessEpisode(currentUser(r), guid) {
+		http.Error(w, "Episode not found", http.StatusNotFound)
+		return
+	}
+
+	switch parts[3] {
+	case "chapters":
+		episodeChaptersHandler(w, r, guid)
+	case "played":
+		episodePlayedHandler(w, r, guid)
+	case "playback":
+		episodePlaybackHandler(w, r, guid)
+	case "state":
+		episodeStateHandler(w, r, guid)
+	case "transcript":
+		episodeTranscriptRouter(w, r, guid, parts[4:])
+	case "tag-suggestions":
+		episodeTagSuggestionsHandler(w, r, guid, parts[4:])
+	default:
+		if format, ok := strings.CutPrefix(parts[3], "transcript."); ok && len(parts) == 4 {
+			transcriptExportHandler(w, r, guid, format)
+			return
+		}
+		http.NotFound(w, r)
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/base64"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestDecodeEpisodeCursor(t *testing.T) {
+	pubDate := time.Date(2024, 3, 1, 12, 30, 0, 500, time.UTC)
+	valid := encodeEpisodeCursor(Episode{GUID: "ep|1", PubDate: pubDate})
+	offset := base64.RawURLEncoding.EncodeToString([]byte("2024-03-01T20:30:00+08:00|ep-2"))
+
+	tests := []struct {
+		name    string
+		cursor  string
+		want    *episodeCursor
+		wantErr bool
+	}{
+		{name: "round trip", cursor: valid, want: &episodeCursor{PubDate: pubDate, GUID: "ep|1"}},
+		{name: "offset normalized to UTC", cursor: offset, want: &episodeCursor{PubDate: time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC), GUID: "ep-2"}},
+		{name: "not base64", cursor: "!!!", wantErr: true},
+		{name: "missing separator", cursor: base64.RawURLEncoding.EncodeToString([]byte("2024-03-01T00:00:00Z")), wantErr: true},
+		{name: "bad date", cursor: base64.RawURLEncoding.EncodeToString([]byte("yesterday|ep")), wantErr: true},
+		{name: "empty", cursor: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeEpisodeCursor(tt.cursor)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got.GUID != tt.want.GUID || !got.PubDate.Equal(tt.want.PubDate) || got.PubDate.Location() != time.UTC {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseEpisodeListOptions(t *testing.T) {
+	day := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 0, 0, 0, 0, time.UTC) }
+
+	tests := []struct {
+		name    string
+		query   string
+		wantErr bool
+		check   func(t *testing.T, opts *episodeListOptions)
+	}{
+		{name: "defaults", query: "", check: func(t *testing.T, opts *episodeListOptions) {
+			if opts.Limit != defaultEpisodePageSize || opts.Ascending || opts.Cursor != nil || opts.IncludeRemoved || opts.IncludeArchived {
+				t.Fatalf("unexpected defaults: %+v", opts)
+			}
+		}},
+		{name: "limit capped", query: "limit=10000", check: func(t *testing.T, opts *episodeListOptions) {
+			if opts.Limit != maxEpisodePageSize {
+				t.Fatalf("limit = %d, want %d", opts.Limit, maxEpisodePageSize)
+			}
+		}},
+		{name: "zero limit", query: "limit=0", wantErr: true},
+		{name: "non-numeric limit", query: "limit=ten", wantErr: true},
+		{name: "ascending", query: "order=asc", check: func(t *testing.T, opts *episodeListOptions) {
+			if !opts.Ascending {
+				t.Fatal("expected ascending order")
+			}
+		}},
+		{name: "bad order", query: "order=up", wantErr: true},
+		{name: "bad cursor", query: "cursor=abc", wantErr: true},
+		{name: "statuses", query: "transcription_status=completed,+none", check: func(t *testing.T, opts *episodeListOptions) {
+			if len(opts.Statuses) != 2 || opts.Statuses[0] != "completed" || opts.Statuses[1] != "" {
+				t.Fatalf("statuses = %q", opts.Statuses)
+			}
+		}},
+		{name: "booleans", query: "has_subtitles=true&played=false&include_removed=1", check: func(t *testing.T, opts *episodeListOptions) {
+			if opts.HasSubtitles == nil || !*opts.HasSubtitles || opts.Played == nil || *opts.Played || !opts.IncludeRemoved {
+				t.Fatalf("unexpected booleans: %+v", opts)
+			}
+		}},
+		{name: "bad boolean", query: "starred=maybe", wantErr: true},
+		{name: "date-only until includes whole day", query: "since=2024-03-01&until=2024-03-05", check: func(t *testing.T, opts *episodeListOptions) {
+			if !opts.Since.Equal(day(2024, 3, 1)) {
+				t.Fatalf("since = %v", opts.Since)
+			}
+			if !opts.Until.Equal(day(2024, 3, 6)) {
+				t.Fatalf("until = %v, want start of next day", opts.Until)
+			}
+		}},
+		{name: "RFC3339 until is exact and UTC", query: "until=" + url.QueryEscape("2024-03-05T08:00:00+08:00"), check: func(t *testing.T, opts *episodeListOptions) {
+			if !opts.Until.Equal(day(2024, 3, 5)) || opts.Until.Location() != time.UTC {
+				t.Fatalf("until = %v", opts.Until)
+			}
+		}},
+		{name: "bad date", query: "since=03/01/2024", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := url.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			opts, err := parseEpisodeListOptions(q)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %+v", opts)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			tt.check(t, opts)
+		})
+	}
+}
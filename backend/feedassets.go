@@ -0,0 +1,81 @@
+package main
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// feed 附带资源的类型
+const (
+	FeedAssetTranscript = "transcript"
+	FeedAssetChapters   = "chapters"
+)
+
+// 刷新时发现的发布方字幕或章节，由后台逐个抓取
+type FeedAssetTask struct {
+	GUID string
+	Kind string
+	URL  string
+	Type string // 字幕的 MIME 类型
+}
+
+// feed 资源抓取队列
+type FeedAssetQueue struct {
+	tasks []FeedAssetTask
+	mu    sync.Mutex
+}
+
+var feedAssetQueue *FeedAssetQueue
+
+// 初始化 feed 资源队列
+func initFeedAssetQueue() {
+	feedAssetQueue = &FeedAssetQueue{tasks: make([]FeedAssetTask, 0)}
+	log.Printf("📎 Feed asset queue initialized")
+
+	go feedAssetWorker()
+}
+
+// 添加任务，同一节目的同类资源只保留一个
+func (q *FeedAssetQueue) AddTask(task FeedAssetTask) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for i, t := range q.tasks {
+		if t.GUID == task.GUID && t.Kind == task.Kind {
+			q.tasks[i] = task
+			return
+		}
+	}
+	q.tasks = append(q.tasks, task)
+}
+
+func (q *FeedAssetQueue) GetNextTask() *FeedAssetTask {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.tasks) == 0 {
+		return nil
+	}
+	task := q.tasks[0]
+	q.tasks = q.tasks[1:]
+	return &task
+}
+
+// 后台抓取处理器
+func feedAssetWorker() {
+	for {
+		task := feedAssetQueue.GetNextTask()
+		if task == nil {
+			time.Sleep(5 * time.Second)
+			continue
+		}
+
+		switch task.Kind {
+		case FeedAssetTranscript:
+			ingestPublisherTranscript(task.GUID, &transcriptLink{URL: task.URL, Type: task.Type})
+		case FeedAssetChapters:
+			ingestFeedChapters(task.GUID, task.URL)
+		}
+	}
+}
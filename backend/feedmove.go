@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+)
+
+// 订阅地址变更原因
+const (
+	FeedMoveRedirect   = "redirect"     // 301/308 永久重定向
+	FeedMoveNewFeedURL = "new-feed-url" // itunes:new-feed-url
+	FeedMoveManual     = "manual"       // 用户手动修改
+)
+
+// 订阅地址变更记录
+type FeedURLHistory struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	ChannelID string    `json:"channel_id" gorm:"index"`
+	OldURL    string    `json:"old_url"`
+	NewURL    string    `json:"new_url"`
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func recordFeedMove(channelID, oldURL, newURL, reason string) {
+	db.Create(&FeedURLHistory{ChannelID: channelID, OldURL: oldURL, NewURL: newURL, Reason: reason})
+}
+
+// 重定向链全部为永久重定向时才跟随地址变更，临时重定向不修改订阅地址
+func permanentRedirectTracker(permanent *bool) func(req *http.Request, via []*http.Request) error {
+	return func(req *http.Request, via []*http.Request) error {
+		if len(via) >= 10 {
+			return http.ErrUseLastResponse
+		}
+		if req.Response == nil ||
+			(req.Response.StatusCode != http.StatusMovedPermanently && req.Response.StatusCode != http.StatusPermanentRedirect) {
+			*permanent = false
+		}
+		return nil
+	}
+}
+
+// 将频道的订阅地址改为新地址，新地址已被其他频道使用时放弃
+func moveChannelFeed(channel *Channel, newURL, reason string, keepValidators bool) bool {
+	newURL = strings.TrimSpace(newURL)
+	if newURL == "" || newURL == channel.RSS {
+		return false
+	}
+	var existing Channel
+	if db.Where("rss = ? AND id <> ?", newURL, channel.ID).Limit(1).Find(&existing).RowsAffected > 0 {
+		log.Printf("⚠️ Feed %s moved to %s, but that URL belongs to channel %s", channel.RSS, newURL, existing.ID)
+		return false
+	}
+
+	updates := map[string]interface{}{"rss": newURL}
+	if !keepValidators {
+		updates["etag"] = ""
+		updates["last_modified"] = ""
+	}
+	if err := db.Model(&Channel{}).Where("id = ?", channel.ID).Updates(updates).Error; err != nil {
+		log.Printf("❌ Failed to move feed of %s: %v", channel.ID, err)
+		return false
+	}
+	recordFeedMove(channel.ID, channel.RSS, newURL, reason)
+	log.Printf("🚚 Feed moved (%s): %s -> %s", reason, channel.RSS, newURL)
+
+	channel.RSS = newURL
+	if !keepValidators {
+		channel.ETag = ""
+		channel.LastModified = ""
+	}
+	return true
+}
+
+// 处理 itunes:new-feed-url：先校验新地址可用，成功后返回新地址的 feed
+func followNewFeedURL(channel *Channel, feed *gofeed.Feed) *gofeed.Feed {
+	if feed.ITunesExt == nil {
+		return nil
+	}
+	newURL := strings.TrimSpace(feed.ITunesExt.NewFeedURL)
+	if newURL == "" || newURL == channel.RSS {
+		return nil
+	}
+
+	newFeed, err := fetchAndValidateFeed(newURL)
+	if err != nil {
+		log.Printf("⚠️ Ignoring new-feed-url %s for %s: %v", newURL, channel.ID, err)
+		return nil
+	}
+	// 新地址又指回旧地址时不跟随，避免来回切换
+	if newFeed.ITunesExt != nil && strings.TrimSpace(newFeed.ITunesExt.NewFeedURL) == channel.RSS {
+		log.Printf("⚠️ Ignoring new-feed-url loop between %s and %s", channel.RSS, newURL)
+		return nil
+	}
+	if !moveChannelFeed(channel, newURL, FeedMoveNewFeedURL, false) {
+		return nil
+	}
+	return newFeed
+}
+
+// 订阅地址变更历史 API
+func channelFeedHistoryHandler(w http.ResponseWriter, r *http.Request, channelID string) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var count int64
+	db.Model(&Channel{}).Where("id = ?", channelID).Count(&count)
+	if count == 0 {
+		http.Error(w, "Channel not found", http.StatusNotFound)
+		return
+	}
+
+	history := []FeedURLHistory{}
+	db.Where("channel_id = ?", channelID).Order("id desc").Find(&history)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"history": history,
+	})
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+)
+
+const (
+	// 退避上限
+	maxRefreshBackoff = 24 * time.Hour
+	// 每个频道保留的失败记录条数
+	feedFailureHistoryLimit = 20
+	// 连续失败达到该次数视为订阅失效
+	failingThreshold = 3
+)
+
+// feed 拉取失败记录
+type FeedFailure struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	ChannelID  string    `json:"channel_id" gorm:"index"`
+	Error      string    `json:"error" gorm:"type:text"`
+	HTTPStatus int       `json:"http_status" gorm:"column:http_status"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// 指数退避：interval * 2^failures，不超过 maxRefreshBackoff
+func backoffInterval(interval time.Duration, failures int) time.Duration {
+	backoff := interval
+	for i := 0; i < failures && backoff < maxRefreshBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxRefreshBackoff {
+		backoff = maxRefreshBackoff
+	}
+	return backoff
+}
+
+// 记录拉取成功，重置失败计数
+func recordFeedSuccess(channel *Channel, status int) {
+	now := time.Now()
+	channel.LastSuccessAt = &now
+	channel.LastHTTPStatus = status
+	channel.ConsecutiveFailures = 0
+	db.Model(&Channel{}).Where("id = ?", channel.ID).UpdateColumns(map[string]interface{}{
+		"last_success_at":      now,
+		"last_http_status":     status,
+		"consecutive_failures": 0,
+	})
+}
+
+// 记录拉取失败，并写入失败历史
+func recordFeedFailure(channel *Channel, status int, fetchErr error) {
+	now := time.Now()
+	channel.LastError = fetchErr.Error()
+	channel.LastErrorAt = &now
+	channel.LastHTTPStatus = status
+	channel.ConsecutiveFailures++
+	db.Model(&Channel{}).Where("id = ?", channel.ID).UpdateColumns(map[string]interface{}{
+		"last_error":           channel.LastError,
+		"last_error_at":        now,
+		"last_http_status":     status,
+		"consecutive_failures": channel.ConsecutiveFailures,
+	})
+
+	db.Create(&FeedFailure{
+		ChannelID:  channel.ID,
+		Error:      channel.LastError,
+		HTTPStatus: status,
+	})
+
+	// 只保留最近的失败记录
+	var ids []uint
+	db.Model(&FeedFailure{}).Where("channel_id = ?", channel.ID).Order("id desc").Pluck("id", &ids)
+	if len(ids) > feedFailureHistoryLimit {
+		db.Delete(&FeedFailure{}, ids[feedFailureHistoryLimit:])
+	}
+
+	log.Printf("⚠️ Feed %s failed %d time(s) in a row (HTTP %d): %v", channel.ID, channel.ConsecutiveFailures, status, fetchErr)
+}
+
+// 频道健康状态：healthy / degraded / failing / unknown
+func channelHealthStatus(channel *Channel) string {
+	switch {
+	case channel.ConsecutiveFailures >= failingThreshold:
+		return "failing"
+	case channel.ConsecutiveFailures > 0:
+		return "degraded"
+	case channel.LastSuccessAt == nil:
+		return "unknown"
+	default:
+		return "healthy"
+	}
+}
+
+// 频道健康状态 API
+func channelHealthHandler(w http.ResponseWriter, r *http.Request, channelID string) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var channel Channel
+	if result := db.First(&channel, "id = ?", channelID); result.Error != nil {
+		http.Error(w, "Channel not found", http.StatusNotFound)
+		return
+	}
+
+	var failures []FeedFailure
+	db.Where("channel_id = ?", channel.ID).Order("id desc").Find(&failures)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"channel_id":           channel.ID,
+		"name":                 channel.Name,
+		"rss":                  channel.RSS,
+		"status":               channelHealthStatus(&channel),
+		"last_success_at":      channel.LastSuccessAt,
+		"last_error":           channel.LastError,
+		"last_error_at":        channel.LastErrorAt,
+		"last_http_status":     channel.LastHTTPStatus,
+		"consecutive_failures": channel.ConsecutiveFailures,
+		"last_refresh_at":      channel.LastRefreshAt,
+		"next_refresh_at":      channel.NextRefreshAt,
+		"fetch_count":          channel.FetchCount,
+		"not_modified_count":   channel.NotModifiedCount,
+		"recent_failures":      failures,
+	})
+}
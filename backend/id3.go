@@ -0,0 +1,250 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"unicode/utf16"
+)
+
+// 标签大小上限，超过视为损坏
+const maxID3TagSize = 64 << 20
+
+// ID3v2 帧
+type id3Frame struct {
+	ID   string
+	Data []byte
+}
+
+// ID3 CHAP 帧解析结果
+type id3Chapter struct {
+	ElementID string
+	StartMs   uint32
+	EndMs     uint32
+	Title     string
+	URL       string
+}
+
+// 读取 ID3v2 标签中的所有帧，只支持 v2.3 和 v2.4
+func readID3Frames(path string) ([]id3Frame, byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer f.Close()
+
+	header := make([]byte, 10)
+	if _, err := io.ReadFull(f, header); err != nil {
+		return nil, 0, err
+	}
+	if string(header[:3]) != "ID3" {
+		return nil, 0, fmt.Errorf("no ID3v2 tag")
+	}
+	version := header[3]
+	if version != 3 && version != 4 {
+		return nil, 0, fmt.Errorf("unsupported ID3v2.%d tag", version)
+	}
+	flags := header[5]
+	size := syncsafeInt(header[6:10])
+	if size > maxID3TagSize {
+		return nil, 0, fmt.Errorf("ID3 tag too large: %d bytes", size)
+	}
+
+	tag := make([]byte, size)
+	if _, err := io.ReadFull(f, tag); err != nil {
+		return nil, 0, err
+	}
+	// v2.3 的不同步处理作用于整个标签
+	if flags&0x80 != 0 && version == 3 {
+		tag = bytes.ReplaceAll(tag, []byte{0xFF, 0x00}, []byte{0xFF})
+	}
+	// 跳过扩展头
+	if flags&0x40 != 0 && len(tag) >= 4 {
+		extSize := int(binary.BigEndian.Uint32(tag[:4])) + 4
+		if version == 4 {
+			extSize = syncsafeInt(tag[:4])
+		}
+		if extSize > len(tag) {
+			return nil, 0, fmt.Errorf("invalid ID3 extended header")
+		}
+		tag = tag[extSize:]
+	}
+
+	return parseID3Frames(tag, version), version, nil
+}
+
+// 解析连续的帧，CHAP/CTOC 的子帧也使用同样的结构
+func parseID3Frames(data []byte, version byte) []id3Frame {
+	var frames []id3Frame
+	for len(data) >= 10 && data[0] != 0 {
+		id := string(data[:4])
+		size := int(binary.BigEndian.Uint32(data[4:8]))
+		if version == 4 {
+			size = syncsafeInt(data[4:8])
+		}
+		if size < 0 || 10+size > len(data) {
+			break
+		}
+		frames = append(frames, id3Frame{ID: id, Data: data[10 : 10+size]})
+		data = data[10+size:]
+	}
+	return frames
+}
+
+func syncsafeInt(b []byte) int {
+	return int(b[0]&0x7F)<<21 | int(b[1]&0x7F)<<14 | int(b[2]&0x7F)<<7 | int(b[3]&0x7F)
+}
+
+// 按编码字节解码文本：0 ISO-8859-1，1 UTF-16 带 BOM，2 UTF-16BE，3 UTF-8
+func decodeID3Text(enc byte, data []byte) string {
+	switch enc {
+	case 1, 2:
+		bigEndian := enc == 2
+		if len(data) >= 2 {
+			if data[0] == 0xFF && data[1] == 0xFE {
+				bigEndian, data = false, data[2:]
+			} else if data[0] == 0xFE && data[1] == 0xFF {
+				bigEndian, data = true, data[2:]
+			}
+		}
+		units := make([]uint16, 0, len(data)/2)
+		for i := 0; i+1 < len(data); i += 2 {
+			var u uint16
+			if bigEndian {
+				u = binary.BigEndian.Uint16(data[i:])
+			} else {
+				u = binary.LittleEndian.Uint16(data[i:])
+			}
+			if u == 0 {
+				break
+			}
+			units = append(units, u)
+		}
+		return string(utf16.Decode(units))
+	case 3:
+		return strings.TrimRight(string(data), "\x00")
+	default:
+		runes := make([]rune, 0, len(data))
+		for _, b := range data {
+			if b == 0 {
+				break
+			}
+			runes = append(runes, rune(b))
+		}
+		return string(runes)
+	}
+}
+
+// 读取以 0 结尾的 ISO-8859-1 字符串
+func readNullTerminated(data []byte) (string, []byte) {
+	i := bytes.IndexByte(data, 0)
+	if i < 0 {
+		return string(data), nil
+	}
+	return string(data[:i]), data[i+1:]
+}
+
+// 跳过按编码结尾的描述字段（UTF-16 以两个 0 字节结尾）
+func skipID3Description(enc byte, data []byte) []byte {
+	if enc == 1 || enc == 2 {
+		for i := 0; i+1 < len(data); i += 2 {
+			if data[i] == 0 && data[i+1] == 0 {
+				return data[i+2:]
+			}
+		}
+		return nil
+	}
+	_, rest := readNullTerminated(data)
+	return rest
+}
+
+func parseID3Chapter(data []byte, version byte) (*id3Chapter, error) {
+	elementID, rest := readNullTerminated(data)
+	if len(rest) < 16 {
+		return nil, fmt.Errorf("CHAP frame too short")
+	}
+	ch := &id3Chapter{
+		ElementID: elementID,
+		StartMs:   binary.BigEndian.Uint32(rest[0:4]),
+		EndMs:     binary.BigEndian.Uint32(rest[4:8]),
+	}
+	for _, sub := range parseID3Frames(rest[16:], version) {
+		if len(sub.Data) == 0 {
+			continue
+		}
+		switch sub.ID {
+		case "TIT2":
+			ch.Title = strings.TrimSpace(decodeID3Text(sub.Data[0], sub.Data[1:]))
+		case "WXXX":
+			url, _ := readNullTerminated(skipID3Description(sub.Data[0], sub.Data[1:]))
+			ch.URL = strings.TrimSpace(url)
+		}
+	}
+	return ch, nil
+}
+
+// 解析顶层 CTOC 的子元素顺序
+func parseID3TOC(data []byte) (topLevel bool, children []string) {
+	_, rest := readNullTerminated(data)
+	if len(rest) < 2 {
+		return false, nil
+	}
+	topLevel = rest[0]&0x02 != 0
+	count := int(rest[1])
+	rest = rest[2:]
+	for i := 0; i < count && len(rest) > 0; i++ {
+		var id string
+		id, rest = readNullTerminated(rest)
+		children = append(children, id)
+	}
+	return topLevel, children
+}
+
+// 从音频文件的 ID3v2 CHAP/CTOC 帧中读取章节
+func readID3Chapters(path string) ([]id3Chapter, error) {
+	frames, version, err := readID3Frames(path)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := map[string]*id3Chapter{}
+	var chapters []*id3Chapter
+	var order []string
+	for _, frame := range frames {
+		switch frame.ID {
+		case "CHAP":
+			ch, err := parseID3Chapter(frame.Data, version)
+			if err != nil {
+				continue
+			}
+			byID[ch.ElementID] = ch
+			chapters = append(chapters, ch)
+		case "CTOC":
+			if topLevel, children := parseID3TOC(frame.Data); topLevel || order == nil {
+				order = children
+			}
+		}
+	}
+
+	// 优先按 CTOC 顺序，否则按开始时间排序
+	var result []id3Chapter
+	used := map[string]bool{}
+	for _, id := range order {
+		if ch, ok := byID[id]; ok && !used[id] {
+			result = append(result, *ch)
+			used[id] = true
+		}
+	}
+	var rest []id3Chapter
+	for _, ch := range chapters {
+		if !used[ch.ElementID] {
+			rest = append(rest, *ch)
+		}
+	}
+	sort.SliceStable(rest, func(i, j int) bool { return rest[i].StartMs < rest[j].StartMs })
+	return append(result, rest...), nil
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// 测试用 ID3 帧：v2.4 使用 syncsafe 大小
+func id3TestFrame(version byte, id string, data []byte) []byte {
+	header := make([]byte, 10)
+	copy(header, id)
+	size := uint32(len(data))
+	if version == 4 {
+		size = (size&0x7F)<<0 | (size>>7&0x7F)<<8 | (size>>14&0x7F)<<16 | (size>>21&0x7F)<<24
+	}
+	binary.BigEndian.PutUint32(header[4:8], size)
+	return append(header, data...)
+}
+
+func id3TestTag(version, flags byte, body []byte) []byte {
+	n := len(body)
+	header := []byte{'I', 'D', '3', version, 0, flags,
+		byte(n >> 21 & 0x7F), byte(n >> 14 & 0x7F), byte(n >> 7 & 0x7F), byte(n & 0x7F)}
+	return append(header, body...)
+}
+
+func id3TestChap(version byte, elementID string, startMs, endMs uint32, title, url string) []byte {
+	data := append([]byte(elementID), 0)
+	times := make([]byte, 16)
+	binary.BigEndian.PutUint32(times[0:4], startMs)
+	binary.BigEndian.PutUint32(times[4:8], endMs)
+	binary.BigEndian.PutUint32(times[8:12], 0xFFFFFFFF)
+	binary.BigEndian.PutUint32(times[12:16], 0xFFFFFFFF)
+	data = append(data, times...)
+	if title != "" {
+		data = append(data, id3TestFrame(version, "TIT2", append([]byte{3}, title...))...)
+	}
+	if url != "" {
+		wxxx := append([]byte{0}, "desc\x00"...)
+		data = append(data, id3TestFrame(version, "WXXX", append(wxxx, url...))...)
+	}
+	return id3TestFrame(version, "CHAP", data)
+}
+
+func id3TestTOC(version byte, topLevel bool, children ...string) []byte {
+	flags := byte(0x01)
+	if topLevel {
+		flags |= 0x02
+	}
+	data := append([]byte("toc\x00"), flags, byte(len(children)))
+	for _, c := range children {
+		data = append(append(data, c...), 0)
+	}
+	return id3TestFrame(version, "CTOC", data)
+}
+
+func writeID3TestFile(t *testing.T, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "episode.mp3")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReadID3Chapters(t *testing.T) {
+	v3Body := bytes.Join([][]byte{
+		id3TestFrame(3, "TIT2", []byte("\x00Episode")),
+		id3TestChap(3, "ch1", 60000, 120000, "Second", ""),
+		id3TestChap(3, "ch0", 0, 60000, "First", "https://example.com"),
+		id3TestTOC(3, true, "ch0", "ch1"),
+	}, nil)
+
+	utf16Title := []byte{1, 0xFF, 0xFE, 'H', 0, 'i', 0, 0, 0}
+	v4Chap := append([]byte("c\x00"), make([]byte, 16)...)
+	binary.BigEndian.PutUint32(v4Chap[2:6], 1500)
+	v4Chap = append(v4Chap, id3TestFrame(4, "TIT2", utf16Title)...)
+	v4Body := id3TestFrame(4, "CHAP", v4Chap)
+
+	noTOC := bytes.Join([][]byte{
+		id3TestChap(3, "b", 5000, 0, "B", ""),
+		id3TestChap(3, "a", 1000, 0, "A", ""),
+	}, nil)
+
+	tests := []struct {
+		name   string
+		data   []byte
+		want   []id3Chapter
+		errors bool
+	}{
+		{
+			name: "v2.3 ordered by CTOC",
+			data: id3TestTag(3, 0, v3Body),
+			want: []id3Chapter{
+				{ElementID: "ch0", StartMs: 0, EndMs: 60000, Title: "First", URL: "https://example.com"},
+				{ElementID: "ch1", StartMs: 60000, EndMs: 120000, Title: "Second"},
+			},
+		},
+		{
+			name: "v2.4 syncsafe sizes and UTF-16 title",
+			data: id3TestTag(4, 0, v4Body),
+			want: []id3Chapter{{ElementID: "c", StartMs: 1500, Title: "Hi"}},
+		},
+		{
+			name: "no CTOC sorted by start",
+			data: id3TestTag(3, 0, noTOC),
+			want: []id3Chapter{{ElementID: "a", StartMs: 1000, Title: "A"}, {ElementID: "b", StartMs: 5000, Title: "B"}},
+		},
+		{name: "padding only", data: id3TestTag(3, 0, make([]byte, 32))},
+		{name: "empty file", data: nil, errors: true},
+		{name: "short header", data: []byte("ID3\x03"), errors: true},
+		{name: "not ID3", data: []byte("RIFF0000000000000000"), errors: true},
+		{name: "unsupported version", data: id3TestTag(2, 0, v3Body), errors: true},
+		{name: "tag size beyond file", data: id3TestTag(3, 0, v3Body)[:40], errors: true},
+		{
+			name:   "extended header larger than tag",
+			data:   id3TestTag(3, 0x40, []byte{0, 0, 1, 0, 0, 0}),
+			errors: true,
+		},
+		{
+			name: "frame size larger than tag",
+			data: id3TestTag(3, 0, []byte{'C', 'H', 'A', 'P', 0x7F, 0xFF, 0xFF, 0xFF, 0, 0, 'x'}),
+		},
+		{
+			name: "CHAP frame too short",
+			data: id3TestTag(3, 0, id3TestFrame(3, "CHAP", []byte("ch\x00\x00\x01"))),
+		},
+		{
+			name: "CTOC claims more children than present",
+			data: id3TestTag(3, 0, id3TestFrame(3, "CTOC", []byte("toc\x00\x03\xFFa"))),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readID3Chapters(writeID3TestFile(t, tt.data))
+			if tt.errors {
+				if err == nil {
+					t.Fatalf("expected error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d chapters %+v, want %+v", len(got), got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("chapter %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+// 任意截断都不能导致越界
+func TestReadID3ChaptersTruncated(t *testing.T) {
+	for _, version := range []byte{3, 4} {
+		body := bytes.Join([][]byte{
+			id3TestChap(version, "ch0", 0, 1000, "First", "https://example.com"),
+			id3TestTOC(version, true, "ch0"),
+		}, nil)
+		for n := 0; n <= len(body); n++ {
+			if _, err := readID3Chapters(writeID3TestFile(t, id3TestTag(version, 0, body[:n]))); err != nil {
+				t.Fatalf("v2.%d truncated to %d bytes: %v", version, n, err)
+			}
+		}
+		// 帧大小与截断后的内容一致，子帧解析会读到残缺数据
+		chap := id3TestChap(version, "ch0", 0, 1000, "First", "https://example.com")[10:]
+		for n := 0; n <= len(chap); n++ {
+			frame := id3TestFrame(version, "CHAP", chap[:n])
+			if _, err := readID3Chapters(writeID3TestFile(t, id3TestTag(version, 0, frame))); err != nil {
+				t.Fatalf("v2.%d CHAP truncated to %d bytes: %v", version, n, err)
+			}
+		}
+	}
+}
+
+func TestDecodeID3Text(t *testing.T) {
+	tests := []struct {
+		name string
+		enc  byte
+		data []byte
+		want string
+	}{
+		{name: "latin1", enc: 0, data: []byte{'c', 0xE9, 0, 'x'}, want: "cé"},
+		{name: "utf-16 little endian BOM", enc: 1, data: []byte{0xFF, 0xFE, 'o', 0, 'k', 0}, want: "ok"},
+		{name: "utf-16 big endian BOM", enc: 1, data: []byte{0xFE, 0xFF, 0, 'o', 0, 'k'}, want: "ok"},
+		{name: "utf-16BE without BOM", enc: 2, data: []byte{0, 'h', 0, 'i'}, want: "hi"},
+		{name: "utf-16 odd length", enc: 1, data: []byte{0xFF, 0xFE, 'a', 0, 'b'}, want: "a"},
+		{name: "utf-16 single byte", enc: 1, data: []byte{0xFF}, want: ""},
+		{name: "utf-8", enc: 3, data: []byte("章节\x00"), want: "章节"},
+		{name: "empty", enc: 3, data: nil, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := decodeID3Text(tt.enc, tt.data); got != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSkipID3Description(t *testing.T) {
+	tests := []struct {
+		name string
+		enc  byte
+		data []byte
+		want []byte
+	}{
+		{name: "latin1", enc: 0, data: []byte("desc\x00url"), want: []byte("url")},
+		{name: "utf-16", enc: 1, data: []byte{'d', 0, 0, 0, 'u'}, want: []byte("u")},
+		{name: "utf-16 unterminated", enc: 1, data: []byte{'d', 0, 'e'}, want: nil},
+		{name: "latin1 unterminated", enc: 0, data: []byte("desc"), want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := skipID3Description(tt.enc, tt.data); !bytes.Equal(got, tt.want) {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,352 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+	"gorm.io/gorm"
+)
+
+// 标题 + 发布时间匹配时允许的时间误差
+const titleMatchWindow = 12 * time.Hour
+
+func shortHash(parts ...string) string {
+	sum := sha1.Sum([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:])[:16]
+}
+
+// 音频地址中常见的追踪参数，规范化时去掉
+var (
+	trackingParams = map[string]bool{
+		"aid": true, "ref": true, "fbclid": true, "gclid": true, "mc_cid": true, "mc_eid": true, "_ga": true,
+		"awcollectionid": true, "awepisodeid": true,
+	}
+	trackingParamPrefixes = []string{"utm_", "aw_"}
+)
+
+func isTrackingParam(key string) bool {
+	key = strings.ToLower(key)
+	if trackingParams[key] {
+		return true
+	}
+	for _, prefix := range trackingParamPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// 规范化音频地址：忽略协议、www、主机大小写、追踪参数和参数顺序，避免追踪参数变化导致误判。
+// 其余查询参数保留，部分托管平台用 play?id=123 区分节目
+func normalizeEnclosureURL(raw string) string {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || u.Host == "" {
+		return strings.ToLower(strings.TrimSpace(raw))
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Host), "www.")
+	normalized := host + strings.TrimSuffix(u.Path, "/")
+
+	query := u.Query()
+	for key := range query {
+		if isTrackingParam(key) {
+			query.Del(key)
+		}
+	}
+	if len(query) > 0 {
+		normalized += "?" + query.Encode()
+	}
+	return normalized
+}
+
+// 早期的指纹忽略了全部查询参数，按当前规则重新计算带参数地址的指纹
+func migrateQueryFingerprints() {
+	var episodes []Episode
+	db.Select("guid", "channel_id", "audio_url", "fingerprint").Where("audio_url LIKE ?", "%?%").Find(&episodes)
+
+	updated := 0
+	for _, ep := range episodes {
+		if fp := episodeFingerprint(ep.ChannelID, ep.AudioURL); fp != ep.Fingerprint {
+			db.Model(&Episode{}).Where("guid = ?", ep.GUID).UpdateColumn("fingerprint", fp)
+			updated++
+		}
+	}
+	if updated > 0 {
+		log.Printf("🔑 Recomputed fingerprints of %d episode(s)", updated)
+	}
+}
+
+// 基于音频地址的指纹，用于识别被重新分配 GUID 的同一节目
+func episodeFingerprint(channelID, audioURL string) string {
+	if audioURL == "" {
+		return ""
+	}
+	return shortHash(channelID, normalizeEnclosureURL(audioURL))
+}
+
+// feed 中条目的 GUID；缺失时由音频地址、链接或标题 + 日期生成
+func feedItemGUID(channelID string, item *gofeed.Item, audioURL string, pubDate time.Time) string {
+	if guid := strings.TrimSpace(item.GUID); guid != "" {
+		return guid
+	}
+	switch {
+	case audioURL != "":
+		return "gen-" + shortHash(channelID, normalizeEnclosureURL(audioURL))
+	case item.Link != "":
+		return "gen-" + shortHash(channelID, item.Link)
+	default:
+		return "gen-" + shortHash(channelID, strings.TrimSpace(item.Title), pubDate.Format("2006-01-02"))
+	}
+}
+
+// feed 当前包含的条目 GUID
+func currentFeedGUIDs(channelID string, items []*gofeed.Item) map[string]bool {
+	guids := make(map[string]bool, len(items))
+	for _, item := range items {
+		pubDate := time.Now()
+		if item.PublishedParsed != nil {
+			pubDate = *item.PublishedParsed
+		}
+		guids[feedItemGUID(channelID, item, extractItemMetadata(item).AudioURL, pubDate.UTC())] = true
+	}
+	return guids
+}
+
+// 在频道内查找被重新分配 GUID 的节目，依次按曾用 GUID、音频指纹、标题 + 日期匹配
+func matchSimilarEpisode(channelID, feedGUID, fingerprint, title string, pubDate time.Time) *Episode {
+	var ep Episode
+	if db.Where("channel_id = ? AND feed_guid = ?", channelID, feedGUID).Limit(1).Find(&ep).RowsAffected > 0 {
+		return &ep
+	}
+	if fingerprint != "" && db.Where("channel_id = ? AND fingerprint = ?", channelID, fingerprint).Limit(1).Find(&ep).RowsAffected > 0 {
+		return &ep
+	}
+	if title != "" && db.Where("channel_id = ? AND title = ? AND pub_date BETWEEN ? AND ?",
+		channelID, title, pubDate.Add(-titleMatchWindow), pubDate.Add(titleMatchWindow)).Limit(1).Find(&ep).RowsAffected > 0 {
+		return &ep
+	}
+	return nil
+}
+
+// 解析条目在库中的 GUID，返回 GUID 和已有节目（新节目为 nil）。
+// GUID 已被其他频道占用时加上频道前缀，避免主键冲突
+func resolveEpisodeGUID(channelID, feedGUID, fingerprint, title string, pubDate time.Time) (string, *Episode) {
+	guid := feedGUID
+	var ep Episode
+	if db.Where("guid = ?", guid).Limit(1).Find(&ep).RowsAffected > 0 {
+		if ep.ChannelID == channelID {
+			return ep.GUID, &ep
+		}
+		guid = channelID + ":" + feedGUID
+		if db.Where("guid = ?", guid).Limit(1).Find(&ep).RowsAffected > 0 {
+			return ep.GUID, &ep
+		}
+	}
+
+	if existing := matchSimilarEpisode(channelID, feedGUID, fingerprint, title, pubDate); existing != nil {
+		log.Printf("🔗 Matched re-GUIDed episode %q: %s -> %s", existing.Title, feedGUID, existing.GUID)
+		return existing.GUID, existing
+	}
+	return guid, nil
+}
+
+// 将关联数据从一个节目迁移到另一个节目，合并重复节目时使用
+func reassignEpisodeReferences(tx *gorm.DB, fromGUID, toGUID string) error {
+	var count int64
+	tx.Model(&Chapter{}).Where("episode_guid = ?", toGUID).Count(&count)
+	if count > 0 {
+		if err := tx.Where("episode_guid = ?", fromGUID).Delete(&Chapter{}).Error; err != nil {
+			return err
+		}
+	} else if err := tx.Model(&Chapter{}).Where("episode_guid = ?", fromGUID).Update("episode_guid", toGUID).Error; err != nil {
+		return err
+	}
+	if err := reassignTranscriptSegments(tx, fromGUID, toGUID); err != nil {
+		return err
+	}
+
+	if err := reassignUserRows(tx, &UserEpisode{}, fromGUID, toGUID); err != nil {
+		return err
+	}
+	if err := reassignUserRows(tx, &PlaybackState{}, fromGUID, toGUID); err != nil {
+		return err
+	}
+	if err := reassignPlaylistItems(tx, fromGUID, toGUID); err != nil {
+		return err
+	}
+	if err := reassignEpisodeTags(tx, fromGUID, toGUID); err != nil {
+		return err
+	}
+	return reassignTagSuggestions(tx, fromGUID, toGUID)
+}
+
+// 迁移以 (user_id, episode_guid) 为键的用户数据，目标节目已有记录的用户保留目标的记录
+func reassignUserRows(tx *gorm.DB, model interface{}, fromGUID, toGUID string) error {
+	var taken []uint
+	tx.Model(model).Where("episode_guid = ?", toGUID).Pluck("user_id", &taken)
+	if len(taken) > 0 {
+		if err := tx.Where("episode_guid = ? AND user_id IN ?", fromGUID, taken).Delete(model).Error; err != nil {
+			return err
+		}
+	}
+	return tx.Model(model).Where("episode_guid = ?", fromGUID).Update("episode_guid", toGUID).Error
+}
+
+// 为旧数据补全音频指纹
+func backfillFingerprints(channelID string) {
+	var episodes []Episode
+	db.Select("guid", "audio_url").
+		Where("channel_id = ? AND (fingerprint = '' OR fingerprint IS NULL) AND audio_url <> ''", channelID).
+		Find(&episodes)
+	for _, ep := range episodes {
+		db.Model(&Episode{}).Where("guid = ?", ep.GUID).UpdateColumn("fingerprint", episodeFingerprint(channelID, ep.AudioURL))
+	}
+}
+
+// 选择合并后保留的节目：有字幕 > 有摘要 > 最早入库
+func pickEpisodeToKeep(group []Episode) int {
+	best := 0
+	score := func(ep Episode) int {
+		s := 0
+		if ep.SrtContent != "" {
+			s += 2
+		}
+		if ep.Summary != "" {
+			s++
+		}
+		return s
+	}
+	for i := 1; i < len(group); i++ {
+		if score(group[i]) > score(group[best]) ||
+			(score(group[i]) == score(group[best]) && group[i].CreatedAt.Before(group[best].CreatedAt)) {
+			best = i
+		}
+	}
+	return best
+}
+
+// 合并后保留的 feed GUID：优先取 feed 当前发布的那条，都不在 feed 中时取发布时间最新、其次最晚出现的
+func currentFeedGUID(group []Episode, feedGUIDs map[string]bool) string {
+	var newest *Episode
+	for i := range group {
+		ep := &group[i]
+		if ep.FeedGUID == "" {
+			continue
+		}
+		if feedGUIDs[ep.FeedGUID] {
+			return ep.FeedGUID
+		}
+		if newest == nil || ep.PubDate.After(newest.PubDate) ||
+			(ep.PubDate.Equal(newest.PubDate) && ep.CreatedAt.After(newest.CreatedAt)) {
+			newest = ep
+		}
+	}
+	if newest == nil {
+		return ""
+	}
+	return newest.FeedGUID
+}
+
+// 合并同一频道中音频指纹相同的重复节目，保留字幕、摘要和本地音频。
+// feedGUIDs 为 feed 当前包含的 GUID，其中两条以上仍在 feed 中的一组不合并，它们是不同的节目
+func mergeDuplicateEpisodes(channelID string, feedGUIDs map[string]bool) int {
+	backfillFingerprints(channelID)
+
+	var fingerprints []string
+	db.Model(&Episode{}).
+		Where("channel_id = ? AND fingerprint <> ''", channelID).
+		Group("fingerprint").Having("COUNT(*) > 1").
+		Pluck("fingerprint", &fingerprints)
+
+	merged := 0
+	for _, fp := range fingerprints {
+		var group []Episode
+		db.Where("channel_id = ? AND fingerprint = ?", channelID, fp).Order("updated_at desc").Find(&group)
+		if len(group) < 2 {
+			continue
+		}
+		live := 0
+		for _, ep := range group {
+			if feedGUIDs[ep.FeedGUID] {
+				live++
+			}
+		}
+		if live > 1 {
+			log.Printf("⚠️ %d episodes share fingerprint %s but are all in the feed, not merging", live, fp)
+			continue
+		}
+
+		keepIdx := pickEpisodeToKeep(group)
+		keep := group[keepIdx]
+		updates := map[string]interface{}{}
+		// 合并成功后才删除的本地音频
+		var staleAudio []string
+		if feedGUID := currentFeedGUID(group, feedGUIDs); feedGUID != "" {
+			updates["feed_guid"] = feedGUID
+		}
+		for i, dup := range group {
+			if i == keepIdx {
+				continue
+			}
+			if keep.SrtContent == "" && dup.SrtContent != "" {
+				keep.SrtContent = dup.SrtContent
+				updates["srt_content"] = dup.SrtContent
+				updates["transcript_source"] = dup.TranscriptSource
+				updates["transcript_url"] = dup.TranscriptURL
+				updates["transcription_status"] = dup.TranscriptionStatus
+			}
+			if keep.TranscriptText == "" && dup.TranscriptText != "" {
+				keep.TranscriptText = dup.TranscriptText
+				updates["transcript_text"] = dup.TranscriptText
+			}
+			if keep.Summary == "" && dup.Summary != "" {
+				keep.Summary = dup.Summary
+				updates["summary"] = dup.Summary
+			}
+			if keep.LocalAudioPath == "" && dup.LocalAudioPath != "" {
+				keep.LocalAudioPath = dup.LocalAudioPath
+				updates["local_audio_path"] = dup.LocalAudioPath
+			} else if dup.LocalAudioPath != "" && dup.LocalAudioPath != keep.LocalAudioPath {
+				staleAudio = append(staleAudio, dup.LocalAudioPath)
+			}
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			for i, dup := range group {
+				if i == keepIdx {
+					continue
+				}
+				if err := reassignEpisodeReferences(tx, dup.GUID, keep.GUID); err != nil {
+					return err
+				}
+				if err := tx.Delete(&Episode{}, "guid = ?", dup.GUID).Error; err != nil {
+					return err
+				}
+			}
+			return tx.Model(&Episode{}).Where("guid = ?", keep.GUID).Updates(updates).Error
+		})
+		if err != nil {
+			log.Printf("❌ Failed to merge duplicates of %s: %v", keep.GUID, err)
+			continue
+		}
+		for _, path := range staleAudio {
+			removeCachedAudio(path)
+		}
+		merged += len(group) - 1
+		var dupGUIDs []string
+		for i, dup := range group {
+			if i != keepIdx {
+				dupGUIDs = append(dupGUIDs, dup.GUID)
+			}
+		}
+		removeEpisodesFromSearch(dupGUIDs)
+		indexEpisodesForSearch([]string{keep.GUID})
+		log.Printf("🔀 Merged %d duplicate(s) into %q (%s)", len(group)-1, keep.Title, keep.GUID)
+	}
+	return merged
+}
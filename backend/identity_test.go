@@ -0,0 +1,50 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNormalizeEnclosureURL(t *testing.T) {
+	tests := []struct {
+		a, b string
+		same bool
+	}{
+		{"https://www.Example.com/ep1.mp3", "http://example.com/ep1.mp3", true},
+		{"https://cdn.example.com/ep1.mp3?utm_source=rss&aid=feed", "https://cdn.example.com/ep1.mp3", true},
+		{"https://cdn.example.com/ep1.mp3?awCollectionId=1&awEpisodeId=2", "https://cdn.example.com/ep1.mp3?aw_0_1st.playerid=x", true},
+		{"https://host.example/play?id=1&fmt=mp3", "https://host.example/play?fmt=mp3&id=1&utm_medium=x", true},
+		{"https://host.example/play?id=123", "https://host.example/play?id=124", false},
+		{"https://host.example/play?id=123", "https://host.example/play", false},
+		{"https://host.example/a/ep.mp3", "https://host.example/b/ep.mp3", false},
+		{"https://host.example/Ep.mp3", "https://host.example/ep.mp3", false},
+	}
+	for _, tt := range tests {
+		a, b := normalizeEnclosureURL(tt.a), normalizeEnclosureURL(tt.b)
+		if (a == b) != tt.same {
+			t.Errorf("normalize(%q) = %q, normalize(%q) = %q, same = %v, want %v", tt.a, a, tt.b, b, a == b, tt.same)
+		}
+	}
+}
+
+func TestCurrentFeedGUID(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+	group := []Episode{
+		{GUID: "a", FeedGUID: "old", PubDate: day(3), CreatedAt: day(3)},
+		{GUID: "b", FeedGUID: "live", PubDate: day(1), CreatedAt: day(1)},
+		{GUID: "c", FeedGUID: "", PubDate: day(9)},
+	}
+	if got := currentFeedGUID(group, map[string]bool{"live": true}); got != "live" {
+		t.Fatalf("got %q, want the GUID in the current feed", got)
+	}
+	if got := currentFeedGUID(group, nil); got != "old" {
+		t.Fatalf("got %q, want the newest by pub date", got)
+	}
+	group[1].PubDate, group[1].CreatedAt = day(3), day(5)
+	if got := currentFeedGUID(group, nil); got != "live" {
+		t.Fatalf("got %q, want the last seen on equal pub dates", got)
+	}
+	if got := currentFeedGUID([]Episode{{GUID: "x"}}, nil); got != "" {
+		t.Fatalf("got %q, want empty", got)
+	}
+}
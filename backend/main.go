@@ -16,10 +16,8 @@ import (
 	"time"
 
 	"github.com/glebarez/sqlite"
-	"github.com/mmcdole/gofeed"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
-	"gorm.io/gorm/clause"
 	"sort"
 )
 
@@ -30,23 +28,65 @@ type Channel struct {
 	Author      string    `json:"author"`
 	RSS         string    `json:"rss"`
 	Description string    `json:"description"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	Category    string    `json:"category"`
+	// 刷新间隔（分钟），0 表示使用默认值
+	RefreshInterval int        `json:"refresh_interval"`
+	LastRefreshAt   *time.Time `json:"last_refresh_at"`
+	NextRefreshAt   *time.Time `json:"next_refresh_at" gorm:"index"`
+	// 条件请求的校验值和统计
+	ETag             string    `json:"etag" gorm:"column:etag"`
+	LastModified     string    `json:"last_modified"`
+	FetchCount       int64     `json:"fetch_count"`
+	NotModifiedCount int64     `json:"not_modified_count"`
+	// 健康状态
+	LastSuccessAt       *time.Time `json:"last_success_at"`
+	LastError           string     `json:"last_error" gorm:"type:text"`
+	LastErrorAt         *time.Time `json:"last_error_at"`
+	LastHTTPStatus      int        `json:"last_http_status" gorm:"column:last_http_status"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	AutoDownload        AutoDownloadRule `json:"auto_download" gorm:"embedded;embeddedPrefix:auto_download_"`
+	AutoTranscribe      AutoTranscribePolicy `json:"auto_transcribe" gorm:"embedded;embeddedPrefix:auto_transcribe_"`
+	Profile             ChannelProfile       `json:"profile" gorm:"embedded;embeddedPrefix:profile_"`
+	UpdatedAt           time.Time  `json:"updated_at"`
 }
 
 type Episode struct {
 	GUID          string    `json:"guid" gorm:"primaryKey"`
+	FeedGUID      string    `json:"feed_guid" gorm:"index"`  // feed 当前使用的 GUID，可能与主键不同
+	Fingerprint   string    `json:"-" gorm:"index"`          // 音频地址指纹，用于识别重复节目
 	ChannelID     string    `json:"channel_id" gorm:"index:idx_channel_pubdate"`
 	Title         string    `json:"title"`
 	Description   string    `json:"description" gorm:"type:text"`
 	Link          string    `json:"link"`
 	PubDate       time.Time `json:"pub_date" gorm:"index:idx_channel_pubdate"`
 	AudioURL      string    `json:"audioUrl"` // Standardized to matches frontend expectation
-	Duration      string    `json:"duration"`
+	Duration      string    `json:"duration"` // itunes:duration 原始值
+	DurationSeconds int     `json:"duration_seconds"`
+	EnclosureLength int64   `json:"enclosure_length"` // 字节
+	EnclosureType   string  `json:"enclosure_type"`
+	EpisodeNumber   int     `json:"episode_number"`
+	SeasonNumber    int     `json:"season_number"`
+	EpisodeType     string  `json:"episode_type"` // full / trailer / bonus
+	Explicit        bool    `json:"explicit"`
+	ImageURL        string  `json:"image_url"`
 	LocalAudioPath string   `json:"local_audio_path"`
+	AutoDownloaded bool     `json:"auto_downloaded"` // 由自动下载规则下载
 	SrtContent    string    `json:"srt_content" gorm:"type:text"`
+	TranscriptText string   `json:"-" gorm:"type:text"` // 没有时间轴的发布方字幕（如 HTML），只作为文本使用
 	Summary       string    `json:"summary" gorm:"type:text"`
-	Tags          string    `json:"tags" gorm:"type:text"`
+	Tags          string    `json:"tags" gorm:"-"` // feed_tags 和 user_tags 合并后的逗号分隔字符串
+	FeedTags      []string  `json:"feed_tags" gorm:"-"`
+	UserTags      []string  `json:"user_tags" gorm:"-"`
 	TranscriptionStatus string `json:"transcription_status" gorm:"default:''"`
+	TranscriptSource    string `json:"transcript_source"` // whisper / publisher / upload / manual
+	TranscriptURL       string `json:"transcript_url"`
+	TranscriptStale     bool   `json:"transcript_stale"` // 音频被替换后字幕可能不再匹配
+	RemovedAt     *time.Time `json:"removed_at" gorm:"index"` // 已从 feed 中下架
+	// 当前用户的播放状态，不存库
+	Played        bool       `json:"played" gorm:"-"`
+	PlayedAt      *time.Time `json:"played_at,omitempty" gorm:"-"`
+	Starred       bool       `json:"starred" gorm:"-"`
+	Archived      bool       `json:"archived" gorm:"-"`
 	CreatedAt     time.Time `json:"created_at"`
 	UpdatedAt     time.Time `json:"updated_at"`
 }
@@ -57,6 +97,7 @@ type TranscriptionTask struct {
 	AudioURL  string
 	LocalPath string
 	Title     string
+	Priority  int // 数值越大越先处理
 	AddedAt   time.Time
 }
 
@@ -122,11 +163,19 @@ func initDB() {
 
 	// Auto Migrate
 	log.Printf("🔄 Running database migrations...")
-	err = db.AutoMigrate(&Channel{}, &Episode{})
+	err = db.AutoMigrate(&Channel{}, &Episode{}, &FeedFailure{}, &Chapter{}, &FeedURLHistory{},
+		&User{}, &AuthToken{}, &Subscription{}, &UserEpisode{}, &PlaybackState{}, &Playlist{}, &PlaylistItem{},
+		&Tag{}, &EpisodeTag{}, &TagSuggestion{}, &TranscriptSegment{})
 	if err != nil {
 		log.Fatal("❌ Failed to migrate database:", err)
 	}
 	log.Printf("✅ Database migrations completed")
+	migratePubDatesToUTC()
+	migrateQueryFingerprints()
+	migrateLegacyTags()
+	initSearchIndex()
+	migrateUntimedTranscripts()
+	go backfillTranscriptSegments()
 
 	// Seed initial channels if empty
 	var count int64
@@ -168,9 +217,12 @@ func (q *TranscriptionQueue) AddTask(task TranscriptionTask) {
 	q.mu.Lock()
 	defer q.mu.Unlock()
 	
-	// 检查是否已经在队列中
-	for _, t := range q.tasks {
+	// 检查是否已经在队列中，用户再次提交自动任务时提升优先级
+	for i, t := range q.tasks {
 		if t.GUID == task.GUID {
+			if task.Priority > t.Priority {
+				q.tasks[i].Priority = task.Priority
+			}
 			log.Printf("⏭️  Task already in queue: %s", task.Title)
 			return
 		}
@@ -179,8 +231,8 @@ func (q *TranscriptionQueue) AddTask(task TranscriptionTask) {
 	// 检查是否已经有字幕
 	var episode Episode
 	if err := db.Where("guid = ?", task.GUID).First(&episode).Error; err == nil {
-		if episode.SrtContent != "" {
-			log.Printf("✅ Episode already has subtitles: %s", task.Title)
+		if episode.SrtContent != "" && !episode.TranscriptStale {
+			log.Printf("✅ Episode already has subtitles (%s): %s", episode.TranscriptSource, task.Title)
 			return
 		}
 	}
@@ -199,8 +251,15 @@ func (q *TranscriptionQueue) GetNextTask() *TranscriptionTask {
 		return nil
 	}
 	
-	task := q.tasks[0]
-	q.tasks = q.tasks[1:]
+	// 优先级最高的任务中最早加入的
+	next := 0
+	for i, t := range q.tasks {
+		if t.Priority > q.tasks[next].Priority {
+			next = i
+		}
+	}
+	task := q.tasks[next]
+	q.tasks = append(q.tasks[:next], q.tasks[next+1:]...)
 	return &task
 }
 
@@ -215,6 +274,14 @@ func transcriptionWorker() {
 			continue
 		}
 		
+		// 排队期间可能已经拿到了发布方字幕
+		var current Episode
+		if db.Select("guid", "srt_content", "transcript_source", "transcript_stale").Where("guid = ?", task.GUID).Limit(1).Find(&current).RowsAffected > 0 &&
+			current.SrtContent != "" && !current.TranscriptStale {
+			log.Printf("⏭️  Skipping %s: already has subtitles (%s)", task.Title, current.TranscriptSource)
+			continue
+		}
+
 		log.Printf("🎬 Processing transcription task: %s", task.Title)
 		
 		// 更新状态为处理中
@@ -238,7 +305,7 @@ func transcriptionWorker() {
 		}
 		
 		// 执行转录
-		srtContent, err := performTranscription(task.LocalPath)
+		srtContent, err := performTranscription(task.LocalPath, transcriptionOptionsForEpisode(task.GUID))
 		if err != nil {
 			log.Printf("❌ Transcription failed for %s: %v", task.Title, err)
 			db.Model(&Episode{}).Where("guid = ?", task.GUID).Update("transcription_status", "failed")
@@ -246,12 +313,8 @@ func transcriptionWorker() {
 		}
 		
 		// 保存到数据库
-		result := db.Model(&Episode{}).Where("guid = ?", task.GUID).Updates(map[string]interface{}{
-			"srt_content":          srtContent,
-			"transcription_status": "completed",
-		})
-		if result.Error != nil {
-			log.Printf("❌ Failed to save SRT for %s: %v", task.Title, result.Error)
+		if _, err := saveWhisperTranscript(task.GUID, srtContent); err != nil {
+			log.Printf("❌ Failed to save SRT for %s: %v", task.Title, err)
 		} else {
 			log.Printf("✅ Transcription completed and saved: %s", task.Title)
 		}
@@ -312,11 +375,12 @@ func downloadAudio(audioURL, guid string) (string, error) {
 	}
 
 	log.Printf("✅ Downloaded audio: %s (%.2f MB)", fileName, float64(getFileSize(localPath))/(1024*1024))
+	go importID3Chapters(guid, localPath)
 	return localPath, nil
 }
 
 // 执行转录（独立函数，供队列使用）
-func performTranscription(localPath string) (string, error) {
+func performTranscription(localPath string, opts transcriptionOptions) (string, error) {
 	file, err := os.Open(localPath)
 	if err != nil {
 		return "", fmt.Errorf("failed to open file: %v", err)
@@ -339,8 +403,7 @@ func performTranscription(localPath string) (string, error) {
 		return "", fmt.Errorf("failed to copy file content: %v", err)
 	}
 	
-	writer.WriteField("model", "base")
-	writer.WriteField("response_format", "srt")
+	opts.writeFields(writer)
 	writer.Close()
 
 	// Call Whisper API
@@ -389,8 +452,8 @@ func getFileSize(path string) int64 {
 
 func enableCors(w *http.ResponseWriter) {
 	(*w).Header().Set("Access-Control-Allow-Origin", "*")
-	(*w).Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS, PATCH")
-	(*w).Header().Set("Access-Control-Allow-Headers", "Content-Type")
+	(*w).Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, OPTIONS, PATCH, DELETE")
+	(*w).Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
 }
 
 func listChannelsHandler(w http.ResponseWriter, r *http.Request) {
@@ -399,9 +462,13 @@ func listChannelsHandler(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		return
 	}
+	if r.Method == "POST" {
+		createChannelHandler(w, r)
+		return
+	}
 
 	var channels []Channel
-	db.Find(&channels)
+	userChannelsQuery(currentUser(r)).Find(&channels)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(channels)
 }
@@ -428,97 +495,33 @@ func channelEpisodesHandler(w http.ResponseWriter, r *http.Request) {
          return
     }
 
-    // 智能刷新逻辑：
-    // 1. 明确请求刷新 (?refresh=true)
-    // 2. 没有任何节目数据
-    // 3. 频道超过 1 小时未更新
-    refresh := r.URL.Query().Get("refresh") == "true"
-    var count int64
-    db.Model(&Episode{}).Where("channel_id = ?", channelID).Count(&count)
-    
-    // 检查最后更新时间
-    needsRefresh := refresh || count == 0
-    if !needsRefresh {
-        // 检查频道的 updated_at 时间
-        timeSinceUpdate := time.Since(channel.UpdatedAt)
-        if timeSinceUpdate > 1*time.Hour {
-            needsRefresh = true
-            log.Printf("📡 Channel %s hasn't been updated for %v, refreshing...", channelID, timeSinceUpdate.Round(time.Minute))
+    // 节目由后台调度器定时刷新，这里直接读库；?refresh=true 时立即同步刷新
+    if r.URL.Query().Get("refresh") == "true" {
+        if _, err := refreshChannel(&channel); err != nil {
+            log.Printf("❌ Failed to refresh %s: %v", channel.ID, err)
+            // If refresh fails, we still serve cached episodes
         }
     }
 
-    if needsRefresh {
-        log.Printf("🔄 Fetching latest episodes for channel: %s", channel.Name)
-        
-        fp := gofeed.NewParser()
-        feed, err := fp.ParseURL(channel.RSS)
-        if err != nil {
-            log.Printf("❌ Failed to parse RSS for %s: %v", channel.ID, err)
-            // If parse fails, we still serve cached episodes
-        } else {
-            log.Printf("✅ Fetched %d items from RSS feed: %s", len(feed.Items), channel.Name)
-            
-            // 优化：如果不是初次导入，只处理最新的 50 条，避免全量更新太慢
-            itemsToProcess := feed.Items
-            if count > 0 && len(itemsToProcess) > 50 {
-                itemsToProcess = itemsToProcess[:50]
-                log.Printf("⚡ Optimization: Only processing latest 50 items (database already has data)")
-            }
-
-            // Save episodes
-            newCount := 0
-            updatedCount := 0
-            for _, item := range itemsToProcess {
-                pubDate := time.Now()
-                if item.PublishedParsed != nil {
-                    pubDate = *item.PublishedParsed
-                }
-                
-                audioUrl := ""
-                if len(item.Enclosures) > 0 {
-                    audioUrl = item.Enclosures[0].URL
-                }
-
-                // 检查是否已存在 (使用 Find 避免 record not found 错误日志)
-                var existing Episode
-                result := db.Where("guid = ?", item.GUID).Limit(1).Find(&existing)
-                isNew := result.RowsAffected == 0
-
-                episode := Episode{
-                    GUID:        item.GUID,
-                    ChannelID:   channelID,
-                    Title:       item.Title,
-                    Description: item.Description,
-                    Link:        item.Link,
-                    PubDate:     pubDate,
-                    AudioURL:    audioUrl,
-                    Tags:        strings.Join(item.Categories, ","),
-                }
-                
-                // Upsert
-                result = db.Clauses(clause.OnConflict{
-                    Columns:   []clause.Column{{Name: "guid"}},
-                    DoUpdates: clause.AssignmentColumns([]string{"title", "description", "audio_url", "pub_date", "tags", "updated_at"}),
-                }).Create(&episode)
-                
-                if result.Error == nil {
-                    if isNew {
-                        newCount++
-                    } else {
-                        updatedCount++
-                    }
-                }
-            }
-            
-            // 更新频道的 updated_at 时间
-            db.Model(&channel).Update("updated_at", time.Now())
-            
-            log.Printf("📊 Channel %s: %d new episodes, %d updated", channel.Name, newCount, updatedCount)
-        }
+    opts, err := parseEpisodeListOptions(r.URL.Query())
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusBadRequest)
+        return
     }
 
+    opts.UserID = currentUserID(r)
     var episodes []Episode
-    db.Where("channel_id = ?", channelID).Order("pub_date desc").Limit(50).Find(&episodes)
+    applyEpisodeListOptions(db.Where("channel_id = ?", channelID), opts).Find(&episodes)
+
+    // 多取一条用于判断是否还有下一页
+    hasMore := len(episodes) > opts.Limit
+    if hasMore {
+        episodes = episodes[:opts.Limit]
+    }
+    nextCursor := ""
+    if hasMore {
+        nextCursor = encodeEpisodeCursor(episodes[len(episodes)-1])
+    }
     
     // Check local files existence
     for i := range episodes {
@@ -539,10 +542,15 @@ func channelEpisodesHandler(w http.ResponseWriter, r *http.Request) {
     }
     log.Printf("📋 Fetched %d episodes for channel %s. %d have subtitles.", len(episodes), channelID, srtCount)
 
+    // 标签、摘要和播放状态按用户区分
+    applyUserEpisodeData(currentUser(r), episodes)
+
     w.Header().Set("Content-Type", "application/json")
     json.NewEncoder(w).Encode(map[string]interface{}{
         "success": true,
         "episodes": episodes,
+        "next_cursor": nextCursor,
+        "has_more": hasMore,
     })
 }
 
@@ -560,6 +568,10 @@ func downloadEpisodeHandler(w http.ResponseWriter, r *http.Request) {
         http.Error(w, err.Error(), http.StatusBadRequest)
         return
     }
+    if !canAccessEpisode(currentUser(r), req.GUID) {
+        http.Error(w, "Episode not found", http.StatusNotFound)
+        return
+    }
 
     // Ensure cache dir exists (mapped from docker volume or local)
     cacheDir := "media_cache"
@@ -591,8 +603,8 @@ func downloadEpisodeHandler(w http.ResponseWriter, r *http.Request) {
 
     // Check if already exists
     if _, err := os.Stat(localPath); err == nil {
-         // Update DB just in case
-         db.Model(&Episode{}).Where("guid = ?", req.GUID).Update("local_audio_path", localPath)
+         // Update DB just in case; 手动下载的文件不会被自动下载规则清理
+         db.Model(&Episode{}).Where("guid = ?", req.GUID).Updates(map[string]interface{}{"local_audio_path": localPath, "auto_downloaded": false})
          json.NewEncoder(w).Encode(map[string]string{"path": localPath, "status": "exists"})
          return
     }
@@ -619,10 +631,11 @@ func downloadEpisodeHandler(w http.ResponseWriter, r *http.Request) {
     }
 
     // Update DB
-    result := db.Model(&Episode{}).Where("guid = ?", req.GUID).Update("local_audio_path", localPath)
+    result := db.Model(&Episode{}).Where("guid = ?", req.GUID).Updates(map[string]interface{}{"local_audio_path": localPath, "auto_downloaded": false})
     if result.Error != nil {
         log.Printf("Failed to update episode with local path: %v", result.Error)
     }
+    go importID3Chapters(req.GUID, localPath)
 
     // Return updated episode
     var episode Episode
@@ -708,9 +721,12 @@ func saveSrtHandler(w http.ResponseWriter, r *http.Request) {
         http.Error(w, err.Error(), http.StatusBadRequest)
         return
     }
+    if !canAccessEpisode(currentUser(r), req.GUID) {
+        http.Error(w, "Episode not found", http.StatusNotFound)
+        return
+    }
 
-    result := db.Model(&Episode{}).Where("guid = ?", req.GUID).Update("srt_content", req.SrtContent)
-    if result.Error != nil {
+    if err := saveTranscript(req.GUID, req.SrtContent, TranscriptSourceManual); err != nil {
         http.Error(w, "Database error", http.StatusInternalServerError)
         return
     }
@@ -742,6 +758,10 @@ func uploadSrtHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "GUID is required", http.StatusBadRequest)
 		return
 	}
+	if !canAccessEpisode(currentUser(r), guid) {
+		http.Error(w, "Episode not found", http.StatusNotFound)
+		return
+	}
 
 	file, _, err := r.FormFile("file")
 	if err != nil {
@@ -759,13 +779,8 @@ func uploadSrtHandler(w http.ResponseWriter, r *http.Request) {
 	srtStr := string(content)
 	
 	// Update DB
-	result := db.Model(&Episode{}).Where("guid = ?", guid).Updates(map[string]interface{}{
-		"srt_content":          srtStr,
-		"transcription_status": "completed",
-	})
-	
-	if result.Error != nil {
-		log.Printf("❌ Failed to update SRT via upload for %s: %v", guid, result.Error)
+	if err := saveTranscript(guid, srtStr, TranscriptSourceUpload); err != nil {
+		log.Printf("❌ Failed to update SRT via upload for %s: %v", guid, err)
 		http.Error(w, "Database error", http.StatusInternalServerError)
 		return
 	}
@@ -805,9 +820,16 @@ func updateTagsHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "GUID is required", http.StatusBadRequest)
 		return
 	}
+	if !canAccessEpisode(currentUser(r), req.GUID) {
+		http.Error(w, "Episode not found", http.StatusNotFound)
+		return
+	}
 
-	result := db.Model(&Episode{}).Where("guid = ?", req.GUID).Update("tags", req.Tags)
-	if result.Error != nil {
+	// 提交的是完整的标签列表，feed 分类以外的部分保存为当前用户的标签
+	err := db.Transaction(func(tx *gorm.DB) error {
+		return setUserTags(tx, currentUserID(r), req.GUID, splitTagNames(req.Tags))
+	})
+	if err != nil {
 		http.Error(w, "Database error", http.StatusInternalServerError)
 		return
 	}
@@ -844,8 +866,22 @@ func summarizeHandler(w http.ResponseWriter, r *http.Request) {
 
 	log.Printf("🤖 Received summary request for GUID: %s, Model: %s", req.GUID, req.Model)
 
-	// 1. Check if summary already exists in DB
-	if req.GUID != "" {
+	// 1. Check if summary already exists in DB (登录用户只能看到自己的摘要)
+	user := currentUser(r)
+	if req.GUID != "" && !canAccessEpisode(user, req.GUID) {
+		http.Error(w, "Episode not found", http.StatusNotFound)
+		return
+	}
+	if req.GUID != "" && user != nil {
+		if ue := getUserEpisode(user.ID, req.GUID); ue.Summary != "" {
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"success": true,
+				"summary": ue.Summary,
+				"cached":  true,
+			})
+			return
+		}
+	} else if req.GUID != "" {
 		var episode Episode
 		if err := db.Where("guid = ?", req.GUID).First(&episode).Error; err == nil && episode.Summary != "" {
 			json.NewEncoder(w).Encode(map[string]interface{}{
@@ -864,7 +900,7 @@ func summarizeHandler(w http.ResponseWriter, r *http.Request) {
 
 	// 2. Call LLM to summarize
 	log.Printf("📡 Calling LLM (%s) for summary...", req.Model)
-	summary, err := callLLMForSummary(req.SrtContent, req.APIKey, req.APIBase, req.Model)
+	summary, err := callLLM(buildSummaryPrompt(req.GUID, req.SrtContent), req.APIKey, req.APIBase, req.Model)
 	if err != nil {
 		log.Printf("❌ LLM summary error: %v", err)
 		json.NewEncoder(w).Encode(map[string]interface{}{
@@ -875,7 +911,11 @@ func summarizeHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// 3. Save to DB
-	if req.GUID != "" {
+	if req.GUID != "" && user != nil {
+		ue := getUserEpisode(user.ID, req.GUID)
+		ue.Summary = summary
+		saveUserEpisode(&ue)
+	} else if req.GUID != "" {
 		db.Model(&Episode{}).Where("guid = ?", req.GUID).Update("summary", summary)
 	}
 
@@ -886,7 +926,7 @@ func summarizeHandler(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-func callLLMForSummary(content, customKey, customBase, customModel string) (string, error) {
+func callLLM(prompt, customKey, customBase, customModel string) (string, error) {
 	apiKey := customKey
 	if apiKey == "" {
 		apiKey = os.Getenv("OPENAI_API_KEY")
@@ -912,14 +952,6 @@ func callLLMForSummary(content, customKey, customBase, customModel string) (stri
 		return "", fmt.Errorf("OPENAI_API_KEY not set")
 	}
 
-	// Simple heuristic: Take first 4000 chars of srt to avoid context limit
-	textToSummarize := content
-	if len(textToSummarize) > 8000 {
-		textToSummarize = textToSummarize[:8000]
-	}
-
-	prompt := "你是一个专业的播客文稿摘要助手。请根据以下 SRT 格式的转录文本，生成一份简洁生动的内容摘要。要求：1. 概括核心亮点；2. 使用时间轴标记关键话题（如果有的话）；3. 语言通俗易懂；4. 直接输出摘要内容，不要包含转录格式。\n\n文本内容：\n" + textToSummarize
-
 	// Handle API Base URL trailing slash
 	apiBase = strings.TrimSuffix(apiBase, "/")
 
@@ -1001,6 +1033,10 @@ func transcribeHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	if req.GUID != "" && !canAccessEpisode(currentUser(r), req.GUID) {
+		http.Error(w, "Episode not found", http.StatusNotFound)
+		return
+	}
 
 	log.Printf("🎙️ Starting transcription for: %s (GUID: %s)", req.AudioPath, req.GUID)
 	log.Printf("🌐 WHISPER_SERVER_URL: %s", WHISPER_SERVER_URL)
@@ -1057,9 +1093,8 @@ func transcribeHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	
-	// Add required OpenAI API parameters
-	writer.WriteField("model", "base")  // Use the model available on the server
-	writer.WriteField("response_format", "srt")  // Request SRT format output
+	// Add required OpenAI API parameters (model, language and prompt from the channel profile)
+	transcriptionOptionsForEpisode(req.GUID).writeFields(writer)
 	
 	writer.Close()
 
@@ -1113,6 +1148,9 @@ func transcribeHandler(w http.ResponseWriter, r *http.Request) {
 
 	duration := time.Since(start)
 	srtStr := string(srtContent)
+	if cues, ok := parseWhisperVerboseJSON(srtContent); ok {
+		srtStr = formatSRT(cues)
+	}
 	lineCount := strings.Count(srtStr, "-->")
 	srtSize := len(srtContent)
 	
@@ -1122,12 +1160,8 @@ func transcribeHandler(w http.ResponseWriter, r *http.Request) {
 
 	// 4. Save to DB if GUID provided
 	if req.GUID != "" {
-		result := db.Model(&Episode{}).Where("guid = ?", req.GUID).Updates(map[string]interface{}{
-			"srt_content":          srtStr,
-			"transcription_status": "completed",
-		})
-		if result.Error != nil {
-			log.Printf("⚠️ Failed to update database for GUID %s: %v", req.GUID, result.Error)
+		if _, err := saveWhisperTranscript(req.GUID, string(srtContent)); err != nil {
+			log.Printf("⚠️ Failed to update database for GUID %s: %v", req.GUID, err)
 		} else {
 			log.Printf("💾 Subtitles saved to database for GUID: %s", req.GUID)
 		}
@@ -1166,7 +1200,7 @@ func queueTranscriptionHandler(w http.ResponseWriter, r *http.Request) {
 
 	// 获取节目信息
 	var episode Episode
-	if err := db.Where("guid = ?", req.GUID).First(&episode).Error; err != nil {
+	if err := db.Where("guid = ?", req.GUID).First(&episode).Error; err != nil || !canAccessEpisode(currentUser(r), req.GUID) {
 		http.Error(w, "Episode not found", http.StatusNotFound)
 		return
 	}
@@ -1177,6 +1211,7 @@ func queueTranscriptionHandler(w http.ResponseWriter, r *http.Request) {
 		AudioURL:  req.AudioURL,
 		LocalPath: episode.LocalAudioPath,
 		Title:     req.Title,
+		Priority:  TranscriptionPriorityUser,
 		AddedAt:   time.Now(),
 	}
 	
@@ -1192,9 +1227,12 @@ func queueTranscriptionHandler(w http.ResponseWriter, r *http.Request) {
 func main() {
 	initDB()
 	initTranscriptionQueue()
+	initDownloadQueue()
+	initFeedAssetQueue()
+	startFeedScheduler()
 
 	http.HandleFunc("/api/channels", listChannelsHandler)
-    http.HandleFunc("/api/channels/", channelEpisodesHandler) // Matches /api/channels/{id}/episodes... technically matches anything after
+    http.HandleFunc("/api/channels/", channelRouter) // /api/channels/{id} and /api/channels/{id}/episodes
     http.HandleFunc("/api/download", downloadEpisodeHandler)
     http.HandleFunc("/api/save-srt", saveSrtHandler)
     http.HandleFunc("/api/upload-srt", uploadSrtHandler)
@@ -1202,6 +1240,17 @@ func main() {
     http.HandleFunc("/api/transcribe", transcribeHandler)
     http.HandleFunc("/api/summary", summarizeHandler)
     http.HandleFunc("/api/queue-transcription", queueTranscriptionHandler)
+    http.HandleFunc("/api/episodes/", episodeRouter) // /api/episodes/{guid}/chapters
+    http.HandleFunc("/api/opml/import", opmlImportHandler)
+    http.HandleFunc("/api/opml/export", opmlExportHandler)
+    http.HandleFunc("/api/auth/", authRouter) // register, login, logout, me, tokens
+    http.HandleFunc("/api/continue-listening", continueListeningHandler)
+    http.HandleFunc("/api/playlists", playlistsRouter)
+    http.HandleFunc("/api/playlists/", playlistsRouter) // /api/playlists/{id|up-next}/items
+    http.HandleFunc("/api/search", searchHandler)
+    http.HandleFunc("/api/tags", tagsRouter)
+    http.HandleFunc("/api/tags/", tagsRouter) // apply, merge, {id}
+    http.HandleFunc("/api/tag-suggestions", pendingTagSuggestionsHandler)
     
     // Documentation
     http.HandleFunc("/doc", func(w http.ResponseWriter, r *http.Request) {
@@ -1217,7 +1266,7 @@ func main() {
     
 	port := ":8080"
 	log.Printf("Server starting on port %s...", port)
-	if err := http.ListenAndServe(port, nil); err != nil {
+	if err := http.ListenAndServe(port, withAuth(http.DefaultServeMux)); err != nil {
 		log.Fatal(err)
 	}
 }
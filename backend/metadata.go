@@ -0,0 +1,110 @@
+package main
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/mmcdole/gofeed"
+)
+
+// 条目的音频及 iTunes 元数据
+type itemMetadata struct {
+	AudioURL        string
+	Duration        string
+	DurationSeconds int
+	EnclosureLength int64
+	EnclosureType   string
+	EpisodeNumber   int
+	SeasonNumber    int
+	EpisodeType     string
+	Explicit        bool
+	ImageURL        string
+}
+
+// 优先选择音频类型的 enclosure，否则取第一个
+func pickEnclosure(item *gofeed.Item) *gofeed.Enclosure {
+	var first *gofeed.Enclosure
+	for _, enc := range item.Enclosures {
+		if enc == nil || enc.URL == "" {
+			continue
+		}
+		if strings.HasPrefix(strings.ToLower(enc.Type), "audio/") {
+			return enc
+		}
+		if first == nil {
+			first = enc
+		}
+	}
+	return first
+}
+
+// 解析 itunes:duration，支持 HH:MM:SS、MM:SS 和纯秒数
+func parseITunesDuration(s string) int {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0
+	}
+	parts := strings.Split(s, ":")
+	if len(parts) > 3 {
+		return 0
+	}
+	total := 0.0
+	for _, part := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		// ParseFloat 接受 NaN / Inf，转换成 int 时结果未定义
+		if err != nil || v < 0 || math.IsNaN(v) || math.IsInf(v, 0) {
+			return 0
+		}
+		total = total*60 + v
+	}
+	if total > math.MaxInt32 {
+		return 0
+	}
+	return int(total + 0.5)
+}
+
+func parseExplicit(s string) bool {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "yes", "true", "explicit":
+		return true
+	}
+	return false
+}
+
+func parsePositiveInt(s string) int {
+	n, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
+
+// 提取 enclosure 和 iTunes 扩展中的元数据
+func extractItemMetadata(item *gofeed.Item) itemMetadata {
+	var meta itemMetadata
+	if enc := pickEnclosure(item); enc != nil {
+		meta.AudioURL = enc.URL
+		meta.EnclosureType = strings.TrimSpace(enc.Type)
+		if n, err := strconv.ParseInt(strings.TrimSpace(enc.Length), 10, 64); err == nil && n > 0 {
+			meta.EnclosureLength = n
+		}
+	}
+
+	if it := item.ITunesExt; it != nil {
+		meta.Duration = strings.TrimSpace(it.Duration)
+		meta.DurationSeconds = parseITunesDuration(it.Duration)
+		meta.EpisodeNumber = parsePositiveInt(it.Episode)
+		meta.SeasonNumber = parsePositiveInt(it.Season)
+		meta.EpisodeType = strings.ToLower(strings.TrimSpace(it.EpisodeType))
+		meta.Explicit = parseExplicit(it.Explicit)
+		meta.ImageURL = strings.TrimSpace(it.Image)
+	}
+	if meta.EpisodeType == "" {
+		meta.EpisodeType = "full"
+	}
+	if meta.ImageURL == "" && item.Image != nil {
+		meta.ImageURL = item.Image.URL
+	}
+	return meta
+}
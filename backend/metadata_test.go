@@ -0,0 +1,33 @@
+package main
+
+import "testing"
+
+func TestParseITunesDuration(t *testing.T) {
+	tests := []struct {
+		input string
+		want  int
+	}{
+		{"", 0},
+		{"3600", 3600},
+		{"  42 ", 42},
+		{"90.6", 91},
+		{"05:30", 330},
+		{"1:02:03", 3723},
+		{"01:02:03.4", 3723},
+		{"0:90", 90},
+		{"1:2:3:4", 0},
+		{"-5", 0},
+		{"1:-30", 0},
+		{"abc", 0},
+		{"1:", 0},
+		{":", 0},
+		{"NaN", 0},
+		{"Inf", 0},
+		{"1e300", 0},
+	}
+	for _, tt := range tests {
+		if got := parseITunesDuration(tt.input); got != tt.want {
+			t.Errorf("parseITunesDuration(%q) = %d, want %d", tt.input, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+)
+
+// OPML 文档结构
+type opmlDocument struct {
+	XMLName xml.Name `xml:"opml"`
+	Version string   `xml:"version,attr"`
+	Head    opmlHead `xml:"head"`
+	Body    opmlBody `xml:"body"`
+}
+
+type opmlHead struct {
+	Title       string `xml:"title"`
+	DateCreated string `xml:"dateCreated,omitempty"`
+}
+
+type opmlBody struct {
+	Outlines []opmlOutline `xml:"outline"`
+}
+
+// moltenId 用于在导出/导入之间保留频道 ID
+type opmlOutline struct {
+	Text        string        `xml:"text,attr"`
+	Title       string        `xml:"title,attr,omitempty"`
+	Type        string        `xml:"type,attr,omitempty"`
+	XMLURL      string        `xml:"xmlUrl,attr,omitempty"`
+	Description string        `xml:"description,attr,omitempty"`
+	Category    string        `xml:"category,attr,omitempty"`
+	ChannelID   string        `xml:"moltenId,attr,omitempty"`
+	Outlines    []opmlOutline `xml:"outline"`
+}
+
+// 从 OPML 中解析出的订阅
+type opmlFeed struct {
+	RSS       string
+	Title     string
+	Category  string
+	ChannelID string
+}
+
+type opmlImportResult struct {
+	RSS       string `json:"rss"`
+	Title     string `json:"title,omitempty"`
+	ChannelID string `json:"channel_id,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// 解析 OPML 文件中的订阅
+func parseOPML(r io.Reader) ([]opmlFeed, error) {
+	var doc opmlDocument
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, err
+	}
+	return collectOPMLFeeds(doc.Body.Outlines, "", nil), nil
+}
+
+// 递归收集订阅，文件夹名称作为分类
+func collectOPMLFeeds(outlines []opmlOutline, folder string, feeds []opmlFeed) []opmlFeed {
+	for _, o := range outlines {
+		title := o.Title
+		if title == "" {
+			title = o.Text
+		}
+		if o.XMLURL == "" {
+			// 文件夹
+			sub := strings.TrimSpace(title)
+			if folder != "" && sub != "" {
+				sub = folder + "/" + sub
+			} else if sub == "" {
+				sub = folder
+			}
+			feeds = collectOPMLFeeds(o.Outlines, sub, feeds)
+			continue
+		}
+
+		category := strings.Trim(strings.TrimSpace(o.Category), "/")
+		if category == "" {
+			category = folder
+		}
+		feeds = append(feeds, opmlFeed{
+			RSS:       strings.TrimSpace(o.XMLURL),
+			Title:     strings.TrimSpace(title),
+			Category:  category,
+			ChannelID: strings.TrimSpace(o.ChannelID),
+		})
+	}
+	return feeds
+}
+
+// OPML 导入
+func opmlImportHandler(w http.ResponseWriter, r *http.Request) {
+	enableCors(&w)
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := r.ParseMultipartForm(5 << 20); err != nil {
+		http.Error(w, "Failed to parse form", http.StatusBadRequest)
+		return
+	}
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "File is required", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	feeds, err := parseOPML(file)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid OPML: %v", err), http.StatusBadRequest)
+		return
+	}
+	log.Printf("📥 OPML import: %d feeds found", len(feeds))
+
+	user := currentUser(r)
+	imported := []opmlImportResult{}
+	duplicates := []opmlImportResult{}
+	failed := []opmlImportResult{}
+
+	// 先排除重复项
+	seen := map[string]bool{}
+	var pending []opmlFeed
+	for _, f := range feeds {
+		if f.RSS == "" {
+			continue
+		}
+		if seen[f.RSS] {
+			duplicates = append(duplicates, opmlImportResult{RSS: f.RSS, Title: f.Title})
+			continue
+		}
+		seen[f.RSS] = true
+
+		var existing Channel
+		if db.Where("rss = ?", f.RSS).Limit(1).Find(&existing).RowsAffected > 0 {
+			// 登录用户导入已有频道时直接订阅
+			if user != nil && !canAccessChannel(user, existing.ID) {
+				subscribe(user.ID, existing.ID)
+				imported = append(imported, opmlImportResult{RSS: f.RSS, Title: existing.Name, ChannelID: existing.ID})
+				continue
+			}
+			duplicates = append(duplicates, opmlImportResult{RSS: f.RSS, Title: f.Title, ChannelID: existing.ID})
+			continue
+		}
+		pending = append(pending, f)
+	}
+
+	// 并发拉取 feed，写库保持串行以避免 ID 冲突
+	parsed := make([]*gofeed.Feed, len(pending))
+	errs := make([]error, len(pending))
+	sem := make(chan struct{}, 4)
+	var wg sync.WaitGroup
+	for i, f := range pending {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, rss string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			parsed[i], errs[i] = fetchAndValidateFeed(rss)
+		}(i, f.RSS)
+	}
+	wg.Wait()
+
+	for i, f := range pending {
+		if errs[i] != nil {
+			failed = append(failed, opmlImportResult{RSS: f.RSS, Title: f.Title, Error: errs[i].Error()})
+			continue
+		}
+
+		id := f.ChannelID
+		if id != "" {
+			var existing Channel
+			if db.Where("id = ?", id).Limit(1).Find(&existing).RowsAffected > 0 {
+				id = ""
+			}
+		}
+
+		channel, err := createChannelFromFeed(f.RSS, parsed[i], Channel{
+			ID:       id,
+			Name:     f.Title,
+			Category: f.Category,
+		})
+		if err != nil {
+			failed = append(failed, opmlImportResult{RSS: f.RSS, Title: f.Title, Error: err.Error()})
+			continue
+		}
+		if user != nil {
+			subscribe(user.ID, channel.ID)
+		}
+		imported = append(imported, opmlImportResult{RSS: f.RSS, Title: channel.Name, ChannelID: channel.ID})
+	}
+
+	log.Printf("✅ OPML import: %d imported, %d duplicates, %d failed", len(imported), len(duplicates), len(failed))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":    true,
+		"imported":   imported,
+		"duplicates": duplicates,
+		"failed":     failed,
+	})
+}
+
+// OPML 导出，按分类生成文件夹
+func opmlExportHandler(w http.ResponseWriter, r *http.Request) {
+	enableCors(&w)
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var channels []Channel
+	userChannelsQuery(currentUser(r)).Order("name").Find(&channels)
+	doc := buildOPML(channels)
+
+	w.Header().Set("Content-Type", "text/x-opml; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="molten-subscriptions.opml"`)
+	w.Write([]byte(xml.Header))
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		log.Printf("❌ Failed to encode OPML: %v", err)
+	}
+}
+
+// 按分类生成文件夹，文件夹按名称排序，未分类的频道放在最后
+func buildOPML(channels []Channel) opmlDocument {
+	var root []opmlOutline
+	folders := map[string]*opmlOutline{}
+	var folderNames []string
+	for _, ch := range channels {
+		outline := opmlOutline{
+			Text:        ch.Name,
+			Title:       ch.Name,
+			Type:        "rss",
+			XMLURL:      ch.RSS,
+			Description: ch.Description,
+			Category:    ch.Category,
+			ChannelID:   ch.ID,
+		}
+		if ch.Category == "" {
+			root = append(root, outline)
+			continue
+		}
+		folder, ok := folders[ch.Category]
+		if !ok {
+			folder = &opmlOutline{Text: ch.Category, Title: ch.Category}
+			folders[ch.Category] = folder
+			folderNames = append(folderNames, ch.Category)
+		}
+		folder.Outlines = append(folder.Outlines, outline)
+	}
+
+	sort.Strings(folderNames)
+	var outlines []opmlOutline
+	for _, name := range folderNames {
+		outlines = append(outlines, *folders[name])
+	}
+	outlines = append(outlines, root...)
+
+	return opmlDocument{
+		Version: "2.0",
+		Head: opmlHead{
+			Title:       "Molten Music Subscriptions",
+			DateCreated: time.Now().Format(time.RFC1123Z),
+		},
+		Body: opmlBody{Outlines: outlines},
+	}
+}
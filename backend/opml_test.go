@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func TestParseOPML(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []opmlFeed
+		wantErr bool
+	}{
+		{
+			name: "nested folders",
+			input: `<?xml version="1.0"?><opml version="2.0"><body>
+				<outline text="Tech">
+					<outline text="AI">
+						<outline type="rss" text="Show A" xmlUrl=" https://a.example/feed "/>
+					</outline>
+					<outline type="rss" text="B" title="Show B" xmlUrl="https://b.example/feed" category="/News/"/>
+				</outline>
+				<outline type="rss" text="Show C" xmlUrl="https://c.example/feed" moltenId="show-c"/>
+			</body></opml>`,
+			want: []opmlFeed{
+				{RSS: "https://a.example/feed", Title: "Show A", Category: "Tech/AI"},
+				{RSS: "https://b.example/feed", Title: "Show B", Category: "News"},
+				{RSS: "https://c.example/feed", Title: "Show C", ChannelID: "show-c"},
+			},
+		},
+		{
+			name:  "untitled folder keeps parent",
+			input: `<opml><body><outline text="Tech"><outline text=""><outline text="X" xmlUrl="https://x.example"/></outline></outline></body></opml>`,
+			want:  []opmlFeed{{RSS: "https://x.example", Title: "X", Category: "Tech"}},
+		},
+		{name: "empty body", input: `<opml version="2.0"><head/><body/></opml>`},
+		{name: "empty input", input: ``, wantErr: true},
+		{name: "truncated", input: `<opml><body><outline text="A" xmlUrl="https://a`, wantErr: true},
+		{name: "not OPML", input: `<rss><channel/></rss>`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseOPML(strings.NewReader(tt.input))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("feed %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestOPMLRoundTrip(t *testing.T) {
+	channels := []Channel{
+		{ID: "a", Name: "Alpha", RSS: "https://a.example/feed", Category: "Tech"},
+		{ID: "b", Name: "Beta & Co", RSS: "https://b.example/feed?x=1&y=2"},
+		{ID: "c", Name: "Gamma", RSS: "https://c.example/feed", Category: "Arts"},
+		{ID: "d", Name: "Delta", RSS: "https://d.example/feed", Category: "Tech"},
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	if err := xml.NewEncoder(&buf).Encode(buildOPML(channels)); err != nil {
+		t.Fatal(err)
+	}
+	got, err := parseOPML(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 文件夹按名称排序，未分类的频道在最后
+	want := []opmlFeed{
+		{RSS: "https://c.example/feed", Title: "Gamma", Category: "Arts", ChannelID: "c"},
+		{RSS: "https://a.example/feed", Title: "Alpha", Category: "Tech", ChannelID: "a"},
+		{RSS: "https://d.example/feed", Title: "Delta", Category: "Tech", ChannelID: "d"},
+		{RSS: "https://b.example/feed?x=1&y=2", Title: "Beta & Co", ChannelID: "b"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Errorf("feed %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
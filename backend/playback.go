@@ -0,0 +1,217 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+const (
+	defaultContinueListeningLimit = 20
+	maxContinueListeningLimit     = 100
+	// 播放到剩余不足该比例时视为听完
+	completedThreshold = 0.95
+)
+
+// 播放进度，匿名用户的 user_id 为 0
+type PlaybackState struct {
+	UserID      uint      `json:"user_id" gorm:"primaryKey;autoIncrement:false"`
+	EpisodeGUID string    `json:"episode_guid" gorm:"primaryKey"`
+	Position    float64   `json:"position"` // 秒
+	Duration    float64   `json:"duration"` // 秒
+	Completed   bool      `json:"completed"`
+	UpdatedAt   time.Time `json:"updated_at" gorm:"autoUpdateTime:false;index"`
+}
+
+func currentUserID(r *http.Request) uint {
+	if user := currentUser(r); user != nil {
+		return user.ID
+	}
+	return 0
+}
+
+// 播放进度 API：GET 读取，PUT 按 updated_at 最后写入者胜出
+func episodePlaybackHandler(w http.ResponseWriter, r *http.Request, guid string) {
+	userID := currentUserID(r)
+
+	switch r.Method {
+	case "GET":
+		var state PlaybackState
+		found := db.Where("user_id = ? AND episode_guid = ?", userID, guid).Limit(1).Find(&state).RowsAffected > 0
+		w.Header().Set("Content-Type", "application/json")
+		if !found {
+			json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "state": nil})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "state": state})
+	case "PUT":
+		updatePlaybackState(w, r, userID, guid)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func updatePlaybackState(w http.ResponseWriter, r *http.Request, userID uint, guid string) {
+	var req struct {
+		Position  float64    `json:"position"`
+		Duration  float64    `json:"duration"`
+		Completed *bool      `json:"completed"`
+		UpdatedAt *time.Time `json:"updated_at"` // 客户端记录进度的时间，缺省为服务器时间
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Position < 0 || req.Duration < 0 {
+		http.Error(w, "position and duration must not be negative", http.StatusBadRequest)
+		return
+	}
+
+	var count int64
+	db.Model(&Episode{}).Where("guid = ?", guid).Count(&count)
+	if count == 0 {
+		http.Error(w, "Episode not found", http.StatusNotFound)
+		return
+	}
+
+	// 客户端时钟超前时以服务器时间为准，避免一台设备永远胜出
+	now := time.Now().UTC()
+	updatedAt := now
+	if req.UpdatedAt != nil && req.UpdatedAt.Before(now) {
+		updatedAt = req.UpdatedAt.UTC()
+	}
+
+	state := PlaybackState{
+		UserID:      userID,
+		EpisodeGUID: guid,
+		Position:    req.Position,
+		Duration:    req.Duration,
+		UpdatedAt:   updatedAt,
+	}
+	if req.Completed != nil {
+		state.Completed = *req.Completed
+	} else {
+		state.Completed = req.Duration > 0 && req.Position >= req.Duration*completedThreshold
+	}
+
+	applied, err := writePlaybackState(&state)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if applied {
+		if state.Completed {
+			ue := getUserEpisode(userID, guid)
+			if !ue.Played {
+				ue.Played = true
+				ue.PlayedAt = &now
+				saveUserEpisode(&ue)
+			}
+		}
+	} else {
+		db.Where("user_id = ? AND episode_guid = ?", userID, guid).Limit(1).Find(&state)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"applied": applied, // false 表示已有更新的进度，请求被忽略
+		"state":   state,
+	})
+}
+
+// 写入进度，已有记录比 state 新时不写入。
+// 先按 updated_at 条件更新，没有记录时插入；并发插入冲突后再尝试一次条件更新
+func writePlaybackState(state *PlaybackState) (bool, error) {
+	update := func() (bool, error) {
+		result := db.Model(&PlaybackState{}).
+			Where("user_id = ? AND episode_guid = ? AND updated_at <= ?", state.UserID, state.EpisodeGUID, state.UpdatedAt).
+			Updates(map[string]interface{}{
+				"position":   state.Position,
+				"duration":   state.Duration,
+				"completed":  state.Completed,
+				"updated_at": state.UpdatedAt,
+			})
+		return result.RowsAffected > 0, result.Error
+	}
+
+	if applied, err := update(); applied || err != nil {
+		return applied, err
+	}
+	result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(state)
+	if result.Error != nil || result.RowsAffected > 0 {
+		return result.Error == nil, result.Error
+	}
+	return update()
+}
+
+// 继续收听：未听完的节目，按最近播放时间排序
+func continueListeningHandler(w http.ResponseWriter, r *http.Request) {
+	enableCors(&w)
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	limit := defaultContinueListeningLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			http.Error(w, "invalid limit: "+v, http.StatusBadRequest)
+			return
+		}
+		if n > maxContinueListeningLimit {
+			n = maxContinueListeningLimit
+		}
+		limit = n
+	}
+
+	var states []PlaybackState
+	db.Where("user_id = ? AND completed = ? AND position > 0", currentUserID(r), false).
+		Order("updated_at desc").Limit(limit).Find(&states)
+
+	guids := make([]string, len(states))
+	for i, s := range states {
+		guids[i] = s.EpisodeGUID
+	}
+	var episodes []Episode
+	if len(guids) > 0 {
+		db.Omit("srt_content").Where("guid IN ? AND removed_at IS NULL", guids).Find(&episodes)
+	}
+	byGUID := make(map[string]Episode, len(episodes))
+	for _, ep := range episodes {
+		byGUID[ep.GUID] = ep
+	}
+
+	var ordered []Episode
+	var progress []PlaybackState
+	for _, s := range states {
+		if ep, ok := byGUID[s.EpisodeGUID]; ok {
+			ordered = append(ordered, ep)
+			progress = append(progress, s)
+		}
+	}
+	applyUserEpisodeData(currentUser(r), ordered)
+
+	type continueItem struct {
+		Episode  Episode       `json:"episode"`
+		Playback PlaybackState `json:"playback"`
+	}
+	items := make([]continueItem, len(ordered))
+	for i := range ordered {
+		items[i] = continueItem{Episode: ordered[i], Playback: progress[i]}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"items":   items,
+	})
+}
@@ -0,0 +1,406 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 播放列表类型
+const (
+	PlaylistKindUpNext = "up_next"
+	PlaylistKindCustom = "custom"
+)
+
+// 路径中用 up-next 指代当前用户的待播列表
+const upNextPlaylistRef = "up-next"
+
+// 设置 UP_NEXT_AUTO_DOWNLOAD=true 后加入待播列表的节目会提前下载
+var upNextAutoDownload = getEnv("UP_NEXT_AUTO_DOWNLOAD", "") == "true"
+
+// 播放列表，匿名用户的 user_id 为 0
+type Playlist struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    uint      `json:"user_id" gorm:"index"`
+	Name      string    `json:"name"`
+	Kind      string    `json:"kind"` // up_next / custom
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// 播放列表中的节目，position 从 0 开始连续编号
+type PlaylistItem struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	PlaylistID  uint      `json:"playlist_id" gorm:"index"`
+	EpisodeGUID string    `json:"episode_guid" gorm:"index"`
+	Position    int       `json:"position"`
+	CreatedAt   time.Time `json:"added_at"`
+}
+
+// 获取用户的待播列表，不存在时创建
+func getUpNext(userID uint) (*Playlist, error) {
+	playlist := Playlist{UserID: userID, Kind: PlaylistKindUpNext, Name: "Up Next"}
+	err := db.Where(Playlist{UserID: userID, Kind: PlaylistKindUpNext}).FirstOrCreate(&playlist).Error
+	return &playlist, err
+}
+
+// 按路径中的 ID 或 up-next 查找当前用户的播放列表
+func findPlaylist(userID uint, ref string) (*Playlist, error) {
+	if ref == upNextPlaylistRef {
+		return getUpNext(userID)
+	}
+	id, err := strconv.ParseUint(ref, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid playlist id")
+	}
+	var playlist Playlist
+	if db.Where("id = ? AND user_id = ?", id, userID).Limit(1).Find(&playlist).RowsAffected == 0 {
+		return nil, fmt.Errorf("playlist not found")
+	}
+	return &playlist, nil
+}
+
+// 按给定顺序重新编号
+func writePlaylistOrder(tx *gorm.DB, playlistID uint, guids []string) error {
+	for i, guid := range guids {
+		if err := tx.Model(&PlaylistItem{}).Where("playlist_id = ? AND episode_guid = ?", playlistID, guid).
+			Update("position", i).Error; err != nil {
+			return err
+		}
+	}
+	return tx.Model(&Playlist{}).Where("id = ?", playlistID).Update("updated_at", time.Now()).Error
+}
+
+func playlistGUIDs(tx *gorm.DB, playlistID uint) []string {
+	var guids []string
+	tx.Model(&PlaylistItem{}).Where("playlist_id = ?", playlistID).Order("position, id").Pluck("episode_guid", &guids)
+	return guids
+}
+
+// 插入节目，position 为 nil 时追加到末尾；已在列表中的节目会被移动
+func insertPlaylistItem(playlistID uint, guid string, position *int) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var order []string
+		for _, g := range playlistGUIDs(tx, playlistID) {
+			if g != guid {
+				order = append(order, g)
+			}
+		}
+		var count int64
+		tx.Model(&PlaylistItem{}).Where("playlist_id = ? AND episode_guid = ?", playlistID, guid).Count(&count)
+		if count == 0 {
+			if err := tx.Create(&PlaylistItem{PlaylistID: playlistID, EpisodeGUID: guid}).Error; err != nil {
+				return err
+			}
+		}
+
+		at := len(order)
+		if position != nil && *position >= 0 && *position < at {
+			at = *position
+		}
+		order = append(order[:at], append([]string{guid}, order[at:]...)...)
+		return writePlaylistOrder(tx, playlistID, order)
+	})
+}
+
+func removePlaylistItem(playlistID uint, guid string) (bool, error) {
+	removed := false
+	err := db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("playlist_id = ? AND episode_guid = ?", playlistID, guid).Delete(&PlaylistItem{})
+		if result.Error != nil {
+			return result.Error
+		}
+		removed = result.RowsAffected > 0
+		return writePlaylistOrder(tx, playlistID, playlistGUIDs(tx, playlistID))
+	})
+	return removed, err
+}
+
+// 按完整的 GUID 列表重新排序，列表必须与当前内容一致
+func reorderPlaylist(playlistID uint, guids []string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		current := playlistGUIDs(tx, playlistID)
+		if len(current) != len(guids) {
+			return fmt.Errorf("order must contain all %d items", len(current))
+		}
+		inPlaylist := make(map[string]bool, len(current))
+		for _, g := range current {
+			inPlaylist[g] = true
+		}
+		seen := make(map[string]bool, len(guids))
+		for _, g := range guids {
+			if !inPlaylist[g] || seen[g] {
+				return fmt.Errorf("order must contain each playlist item exactly once")
+			}
+			seen[g] = true
+		}
+		return writePlaylistOrder(tx, playlistID, guids)
+	})
+}
+
+// 合并重复节目时迁移播放列表条目，已包含目标节目的列表直接删除旧条目
+func reassignPlaylistItems(tx *gorm.DB, fromGUID, toGUID string) error {
+	var taken []uint
+	tx.Model(&PlaylistItem{}).Where("episode_guid = ?", toGUID).Pluck("playlist_id", &taken)
+	if len(taken) > 0 {
+		if err := tx.Where("episode_guid = ? AND playlist_id IN ?", fromGUID, taken).Delete(&PlaylistItem{}).Error; err != nil {
+			return err
+		}
+	}
+	return tx.Model(&PlaylistItem{}).Where("episode_guid = ?", fromGUID).Update("episode_guid", toGUID).Error
+}
+
+type playlistEntry struct {
+	PlaylistItem
+	Episode *Episode `json:"episode"`
+}
+
+// 读取播放列表内容，附带节目信息（不含字幕正文）
+func loadPlaylistEntries(r *http.Request, playlistID uint) []playlistEntry {
+	var items []PlaylistItem
+	db.Where("playlist_id = ?", playlistID).Order("position, id").Find(&items)
+	if len(items) == 0 {
+		return []playlistEntry{}
+	}
+
+	guids := make([]string, len(items))
+	for i, item := range items {
+		guids[i] = item.EpisodeGUID
+	}
+	var episodes []Episode
+	db.Omit("srt_content").Where("guid IN ?", guids).Find(&episodes)
+	applyUserEpisodeData(currentUser(r), episodes)
+	byGUID := make(map[string]*Episode, len(episodes))
+	for i := range episodes {
+		byGUID[episodes[i].GUID] = &episodes[i]
+	}
+
+	entries := make([]playlistEntry, len(items))
+	for i, item := range items {
+		entries[i] = playlistEntry{PlaylistItem: item, Episode: byGUID[item.EpisodeGUID]}
+	}
+	return entries
+}
+
+// 播放列表 API：/api/playlists 和 /api/playlists/{id|up-next}[/items[/{guid}]]
+func playlistsRouter(w http.ResponseWriter, r *http.Request) {
+	enableCors(&w)
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.EscapedPath(), "/"), "/")
+	userID := currentUserID(r)
+	if len(parts) == 2 {
+		playlistsHandler(w, r, userID)
+		return
+	}
+
+	playlist, err := findPlaylist(userID, parts[2])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	switch {
+	case len(parts) == 3:
+		playlistHandler(w, r, playlist)
+	case len(parts) == 4 && parts[3] == "items":
+		playlistItemsHandler(w, r, playlist)
+	case len(parts) == 5 && parts[3] == "items":
+		guid, err := url.PathUnescape(parts[4])
+		if err != nil {
+			http.Error(w, "Invalid GUID", http.StatusBadRequest)
+			return
+		}
+		if r.Method != "DELETE" {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		removed, err := removePlaylistItem(playlist.ID, guid)
+		if err != nil {
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		if !removed {
+			http.Error(w, "Episode not in playlist", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]bool{"success": true})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// 列出或创建播放列表
+func playlistsHandler(w http.ResponseWriter, r *http.Request, userID uint) {
+	switch r.Method {
+	case "GET":
+		if _, err := getUpNext(userID); err != nil {
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		var playlists []Playlist
+		db.Where("user_id = ?", userID).Order("kind = 'custom', name").Find(&playlists)
+
+		type playlistSummary struct {
+			Playlist
+			ItemCount int64 `json:"item_count"`
+		}
+		result := make([]playlistSummary, len(playlists))
+		for i, p := range playlists {
+			result[i].Playlist = p
+			db.Model(&PlaylistItem{}).Where("playlist_id = ?", p.ID).Count(&result[i].ItemCount)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success":   true,
+			"playlists": result,
+		})
+	case "POST":
+		var req struct {
+			Name string `json:"name"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		name := strings.TrimSpace(req.Name)
+		if name == "" {
+			http.Error(w, "name is required", http.StatusBadRequest)
+			return
+		}
+		playlist := Playlist{UserID: userID, Name: name, Kind: PlaylistKindCustom}
+		if err := db.Create(&playlist).Error; err != nil {
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success":  true,
+			"playlist": playlist,
+		})
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// 获取、重命名或删除播放列表，待播列表不能重命名和删除
+func playlistHandler(w http.ResponseWriter, r *http.Request, playlist *Playlist) {
+	switch r.Method {
+	case "GET":
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success":  true,
+			"playlist": playlist,
+			"items":    loadPlaylistEntries(r, playlist.ID),
+		})
+	case "PATCH":
+		if playlist.Kind == PlaylistKindUpNext {
+			http.Error(w, "Up Next cannot be renamed", http.StatusBadRequest)
+			return
+		}
+		var req struct {
+			Name string `json:"name"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		name := strings.TrimSpace(req.Name)
+		if name == "" {
+			http.Error(w, "name is required", http.StatusBadRequest)
+			return
+		}
+		if err := db.Model(playlist).Update("name", name).Error; err != nil {
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success":  true,
+			"playlist": playlist,
+		})
+	case "DELETE":
+		if playlist.Kind == PlaylistKindUpNext {
+			http.Error(w, "Up Next cannot be deleted", http.StatusBadRequest)
+			return
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Where("playlist_id = ?", playlist.ID).Delete(&PlaylistItem{}).Error; err != nil {
+				return err
+			}
+			return tx.Delete(playlist).Error
+		})
+		if err != nil {
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]bool{"success": true})
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// POST 插入节目，PUT 按完整 GUID 列表重新排序
+func playlistItemsHandler(w http.ResponseWriter, r *http.Request, playlist *Playlist) {
+	switch r.Method {
+	case "POST":
+		var req struct {
+			GUID     string `json:"guid"`
+			Position *int   `json:"position"` // 缺省追加到末尾，0 表示下一个播放
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var episode Episode
+		if req.GUID == "" || db.Select("guid", "title", "audio_url", "local_audio_path").
+			Where("guid = ?", req.GUID).Limit(1).Find(&episode).RowsAffected == 0 ||
+			!canAccessEpisode(currentUser(r), req.GUID) {
+			http.Error(w, "Episode not found", http.StatusNotFound)
+			return
+		}
+		if err := insertPlaylistItem(playlist.ID, req.GUID, req.Position); err != nil {
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		if playlist.Kind == PlaylistKindUpNext && upNextAutoDownload && episode.AudioURL != "" &&
+			(episode.LocalAudioPath == "" || !fileExists(episode.LocalAudioPath)) {
+			downloadQueue.AddTask(DownloadTask{GUID: episode.GUID, AudioURL: episode.AudioURL, Title: episode.Title})
+		}
+		log.Printf("📝 Added %s to playlist %s", req.GUID, playlist.Name)
+	case "PUT":
+		var req struct {
+			GUIDs []string `json:"guids"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := reorderPlaylist(playlist.ID, req.GUIDs); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"items":   loadPlaylistEntries(r, playlist.ID),
+	})
+}
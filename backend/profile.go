@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"mime/multipart"
+	"regexp"
+	"strings"
+)
+
+// 默认 Whisper 模型
+const defaultWhisperModel = "base"
+
+// 摘要只取字幕前 8000 字节，避免超出上下文
+const maxSummaryContentLength = 8000
+
+// 默认摘要提示词，{{content}} 会被替换为字幕内容
+const defaultSummaryPrompt = "你是一个专业的播客文稿摘要助手。请根据以下 SRT 格式的转录文本，生成一份简洁生动的内容摘要。要求：1. 概括核心亮点；2. 使用时间轴标记关键话题（如果有的话）；3. 语言通俗易懂；4. 直接输出摘要内容，不要包含转录格式。\n\n文本内容：\n{{content}}"
+
+// 频道的转录和摘要配置，留空时使用默认值
+type ChannelProfile struct {
+	Language      string `json:"language"`                        // 转录语言，如 en、zh，留空自动识别
+	Prompt        string `json:"prompt" gorm:"type:text"`         // Whisper 初始提示词，可填主持人名字和专有名词
+	WhisperModel  string `json:"whisper_model"`                   // Whisper 模型，默认 base
+	SummaryPrompt string `json:"summary_prompt" gorm:"type:text"` // 摘要提示词模板，支持 {{content}} {{title}} {{channel}}
+}
+
+var languageCodeRe = regexp.MustCompile(`^[a-zA-Z]{2,3}([-_][a-zA-Z]{2,4})?$`)
+
+func (p *ChannelProfile) validate() error {
+	p.Language = strings.TrimSpace(p.Language)
+	p.WhisperModel = strings.TrimSpace(p.WhisperModel)
+	if p.Language != "" && !languageCodeRe.MatchString(p.Language) {
+		return fmt.Errorf("invalid language: %s", p.Language)
+	}
+	return nil
+}
+
+// 对应 Channel 中 profile_ 前缀的列
+func (p ChannelProfile) columns() map[string]interface{} {
+	return map[string]interface{}{
+		"profile_language":       p.Language,
+		"profile_prompt":         p.Prompt,
+		"profile_whisper_model":  p.WhisperModel,
+		"profile_summary_prompt": p.SummaryPrompt,
+	}
+}
+
+// Whisper 请求参数
+type transcriptionOptions struct {
+	Model    string
+	Language string
+	Prompt   string
+}
+
+// 按节目所属频道的配置生成转录参数
+func transcriptionOptionsForEpisode(guid string) transcriptionOptions {
+	opts := transcriptionOptions{Model: defaultWhisperModel}
+	channel := channelForEpisode(guid)
+	if channel == nil {
+		return opts
+	}
+	if channel.Profile.WhisperModel != "" {
+		opts.Model = channel.Profile.WhisperModel
+	}
+	opts.Language = channel.Profile.Language
+	opts.Prompt = channel.Profile.Prompt
+	return opts
+}
+
+// 写入 OpenAI 兼容的转录参数
+func (opts transcriptionOptions) writeFields(writer *multipart.Writer) {
+	model := opts.Model
+	if model == "" {
+		model = defaultWhisperModel
+	}
+	writer.WriteField("model", model)
+	// verbose_json 带有每段的 avg_logprob，用于计算置信度
+	writer.WriteField("response_format", "verbose_json")
+	if opts.Language != "" {
+		writer.WriteField("language", opts.Language)
+	}
+	if opts.Prompt != "" {
+		writer.WriteField("prompt", opts.Prompt)
+	}
+}
+
+func channelForEpisode(guid string) *Channel {
+	if guid == "" {
+		return nil
+	}
+	var episode Episode
+	if db.Select("guid", "channel_id").Where("guid = ?", guid).Limit(1).Find(&episode).RowsAffected == 0 {
+		return nil
+	}
+	var channel Channel
+	if db.Where("id = ?", episode.ChannelID).Limit(1).Find(&channel).RowsAffected == 0 {
+		return nil
+	}
+	return &channel
+}
+
+// 按频道的摘要模板生成提示词，模板中没有 {{content}} 时将字幕追加到末尾
+func buildSummaryPrompt(guid, content string) string {
+	if len(content) > maxSummaryContentLength {
+		content = strings.ToValidUTF8(content[:maxSummaryContentLength], "")
+	}
+
+	template := defaultSummaryPrompt
+	title, channelName := "", ""
+	if guid != "" {
+		var episode Episode
+		if db.Select("guid", "title").Where("guid = ?", guid).Limit(1).Find(&episode).RowsAffected > 0 {
+			title = episode.Title
+		}
+	}
+	if channel := channelForEpisode(guid); channel != nil {
+		channelName = channel.Name
+		if strings.TrimSpace(channel.Profile.SummaryPrompt) != "" {
+			template = channel.Profile.SummaryPrompt
+		}
+	}
+
+	if !strings.Contains(template, "{{content}}") {
+		template += "\n\n{{content}}"
+	}
+	return strings.NewReplacer(
+		"{{title}}", title,
+		"{{channel}}", channelName,
+		"{{content}}", content,
+	).Replace(template)
+}
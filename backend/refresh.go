@@ -0,0 +1,345 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 调度器检查间隔和并发刷新数量
+const (
+	schedulerTick        = time.Minute
+	maxConcurrentRefresh = 3
+)
+
+// 默认刷新间隔（分钟），可通过 FEED_REFRESH_INTERVAL 配置
+var defaultRefreshInterval = getEnvInt("FEED_REFRESH_INTERVAL", 60)
+
+// 正在刷新的频道，避免同一频道被并发刷新
+var refreshingChannels sync.Map
+
+const feedUserAgent = "MoltenMusic/1.0"
+
+type refreshResult struct {
+	NewCount     int  `json:"new"`
+	UpdatedCount int  `json:"updated"`
+	RemovedCount int  `json:"removed"`
+	NotModified  bool `json:"not_modified"`
+}
+
+func getEnvInt(key string, fallback int) int {
+	if value, ok := os.LookupEnv(key); ok {
+		if n, err := strconv.Atoi(value); err == nil && n > 0 {
+			return n
+		}
+	}
+	return fallback
+}
+
+// 计算下一次刷新时间，加入 ±10% 的随机抖动，避免所有频道同时刷新；
+// 连续失败时按指数退避
+func nextRefreshTime(channel *Channel) time.Time {
+	interval := channel.RefreshInterval
+	if interval <= 0 {
+		interval = defaultRefreshInterval
+	}
+	base := time.Duration(interval) * time.Minute
+	if channel.ConsecutiveFailures > 0 {
+		base = backoffInterval(base, channel.ConsecutiveFailures)
+	}
+	jitter := time.Duration(rand.Int63n(int64(base)/5+1)) - base/10
+	return time.Now().Add(base + jitter)
+}
+
+// 启动后台刷新调度器
+func startFeedScheduler() {
+	log.Printf("⏰ Feed scheduler started (default interval: %d min)", defaultRefreshInterval)
+	go func() {
+		runDueRefreshes()
+		ticker := time.NewTicker(schedulerTick)
+		defer ticker.Stop()
+		for range ticker.C {
+			runDueRefreshes()
+		}
+	}()
+}
+
+// 刷新所有到期的频道
+func runDueRefreshes() {
+	var channels []Channel
+	db.Where("next_refresh_at IS NULL OR next_refresh_at <= ?", time.Now()).
+		Order("next_refresh_at").Find(&channels)
+	if len(channels) == 0 {
+		return
+	}
+
+	sem := make(chan struct{}, maxConcurrentRefresh)
+	var wg sync.WaitGroup
+	for i := range channels {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(ch *Channel) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if _, err := refreshChannel(ch); err != nil {
+				log.Printf("❌ Scheduled refresh failed for %s: %v", ch.ID, err)
+			}
+		}(&channels[i])
+	}
+	wg.Wait()
+}
+
+// 拉取并保存频道的最新节目，同时记录刷新时间
+func refreshChannel(channel *Channel) (*refreshResult, error) {
+	if _, busy := refreshingChannels.LoadOrStore(channel.ID, true); busy {
+		return nil, fmt.Errorf("channel %s is already refreshing", channel.ID)
+	}
+	defer refreshingChannels.Delete(channel.ID)
+
+	log.Printf("🔄 Fetching latest episodes for channel: %s", channel.Name)
+
+	fetched, err := fetchFeed(channel)
+	if err != nil {
+		status := 0
+		if fetched != nil {
+			status = fetched.StatusCode
+		}
+		db.Model(&Channel{}).Where("id = ?", channel.ID).UpdateColumn("fetch_count", gorm.Expr("fetch_count + 1"))
+		recordFeedFailure(channel, status, err)
+		markChannelRefreshed(channel)
+		return nil, err
+	}
+	recordFeedSuccess(channel, fetched.StatusCode)
+	if fetched.MovedTo != "" {
+		moveChannelFeed(channel, fetched.MovedTo, FeedMoveRedirect, true)
+	}
+
+	// 304：内容未变化，跳过写库
+	if fetched.NotModified {
+		db.Model(&Channel{}).Where("id = ?", channel.ID).UpdateColumns(map[string]interface{}{
+			"fetch_count":        gorm.Expr("fetch_count + 1"),
+			"not_modified_count": gorm.Expr("not_modified_count + 1"),
+		})
+		channel.FetchCount++
+		channel.NotModifiedCount++
+		markChannelRefreshed(channel)
+		log.Printf("💤 Feed not modified: %s", channel.Name)
+		return &refreshResult{NotModified: true}, nil
+	}
+	log.Printf("✅ Fetched %d items from RSS feed: %s", len(fetched.Feed.Items), channel.Name)
+
+	db.Model(&Channel{}).Where("id = ?", channel.ID).UpdateColumns(map[string]interface{}{
+		"fetch_count":   gorm.Expr("fetch_count + 1"),
+		"etag":          fetched.ETag,
+		"last_modified": fetched.LastModified,
+	})
+	channel.FetchCount++
+	channel.ETag = fetched.ETag
+	channel.LastModified = fetched.LastModified
+
+	feed := fetched.Feed
+	if newFeed := followNewFeedURL(channel, feed); newFeed != nil {
+		feed = newFeed
+	}
+
+	result := applyFeed(channel, feed)
+	markChannelRefreshed(channel)
+	return result, nil
+}
+
+// feed 拉取结果
+type feedFetch struct {
+	Feed         *gofeed.Feed
+	NotModified  bool
+	StatusCode   int
+	ETag         string
+	LastModified string
+	// 经永久重定向后的最终地址，未发生永久重定向时为空
+	MovedTo string
+}
+
+// 使用 If-None-Match / If-Modified-Since 拉取 feed
+func fetchFeed(channel *Channel) (*feedFetch, error) {
+	req, err := http.NewRequest("GET", channel.RSS, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid feed URL: %v", err)
+	}
+	req.Header.Set("User-Agent", feedUserAgent)
+	if channel.ETag != "" {
+		req.Header.Set("If-None-Match", channel.ETag)
+	}
+	if channel.LastModified != "" {
+		req.Header.Set("If-Modified-Since", channel.LastModified)
+	}
+
+	permanent := true
+	client := &http.Client{Timeout: 60 * time.Second, CheckRedirect: permanentRedirectTracker(&permanent)}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch feed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	fetched := &feedFetch{StatusCode: resp.StatusCode}
+	if finalURL := resp.Request.URL.String(); permanent && finalURL != channel.RSS {
+		fetched.MovedTo = finalURL
+	}
+	if resp.StatusCode == http.StatusNotModified {
+		fetched.NotModified = true
+		return fetched, nil
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fetched, fmt.Errorf("feed returned HTTP %d", resp.StatusCode)
+	}
+
+	feed, err := gofeed.NewParser().Parse(resp.Body)
+	if err != nil {
+		return fetched, fmt.Errorf("failed to parse RSS: %v", err)
+	}
+	fetched.Feed = feed
+	fetched.ETag = resp.Header.Get("ETag")
+	fetched.LastModified = resp.Header.Get("Last-Modified")
+	return fetched, nil
+}
+
+// 将解析后的 feed 写入数据库
+func applyFeed(channel *Channel, feed *gofeed.Feed) *refreshResult {
+	var count int64
+	db.Model(&Episode{}).Where("channel_id = ?", channel.ID).Count(&count)
+
+	// 优化：如果不是初次导入，只处理最新的 50 条，避免全量更新太慢
+	itemsToProcess := feed.Items
+	if count > 0 && len(itemsToProcess) > 50 {
+		itemsToProcess = itemsToProcess[:50]
+	}
+
+	newGUIDs, updatedCount := saveFeedItems(channel.ID, itemsToProcess)
+	newCount := len(newGUIDs)
+	merged := mergeDuplicateEpisodes(channel.ID, currentFeedGUIDs(channel.ID, feed.Items))
+	// 下架检测需要完整的条目列表
+	removed := markRemovedEpisodes(channel.ID, feed.Items)
+	log.Printf("📊 Channel %s: %d new episodes, %d updated, %d removed, %d duplicates merged",
+		channel.Name, newCount, updatedCount, removed, merged)
+
+	// 首次导入时所有节目都是新的，只按保留数量等限制处理已有节目
+	if count == 0 {
+		newGUIDs = nil
+	} else if newGUIDs == nil {
+		newGUIDs = []string{}
+	}
+	applyAutoDownloadRule(channel, newGUIDs)
+	applyAutoTranscribePolicy(channel, newGUIDs)
+	return &refreshResult{NewCount: newCount, UpdatedCount: updatedCount, RemovedCount: removed}
+}
+
+// 记录本次刷新时间并安排下一次刷新
+func markChannelRefreshed(channel *Channel) {
+	now := time.Now()
+	next := nextRefreshTime(channel)
+	channel.LastRefreshAt = &now
+	channel.NextRefreshAt = &next
+	db.Model(&Channel{}).Where("id = ?", channel.ID).Updates(map[string]interface{}{
+		"last_refresh_at": now,
+		"next_refresh_at": next,
+	})
+}
+
+// 将 RSS 条目写入数据库，返回新增节目的 GUID 和更新的数量
+func saveFeedItems(channelID string, items []*gofeed.Item) ([]string, int) {
+	var newGUIDs []string
+	var reindex []string
+	updatedCount := 0
+	categories := map[string][]string{}
+	for _, item := range items {
+		pubDate := time.Now()
+		if item.PublishedParsed != nil {
+			pubDate = *item.PublishedParsed
+		}
+		// 统一存为 UTC，保证 pub_date 排序和游标比较一致
+		pubDate = pubDate.UTC()
+
+		meta := extractItemMetadata(item)
+		audioUrl := meta.AudioURL
+
+		// 解析节目身份：GUID 缺失或被重新生成时匹配到已有节目
+		feedGUID := feedItemGUID(channelID, item, audioUrl, pubDate)
+		fingerprint := episodeFingerprint(channelID, audioUrl)
+		guid, matched := resolveEpisodeGUID(channelID, feedGUID, fingerprint, item.Title, pubDate)
+		isNew := matched == nil
+		var existing Episode
+		if matched != nil {
+			existing = *matched
+			if enclosureChanged(&existing, audioUrl, meta.EnclosureLength) {
+				invalidateEpisodeAudio(&existing)
+			}
+		}
+
+		episode := Episode{
+			GUID:        guid,
+			FeedGUID:    feedGUID,
+			Fingerprint: fingerprint,
+			ChannelID:   channelID,
+			Title:       item.Title,
+			Description: item.Description,
+			Link:        item.Link,
+			PubDate:     pubDate,
+			AudioURL:    audioUrl,
+
+			Duration:        meta.Duration,
+			DurationSeconds: meta.DurationSeconds,
+			EnclosureLength: meta.EnclosureLength,
+			EnclosureType:   meta.EnclosureType,
+			EpisodeNumber:   meta.EpisodeNumber,
+			SeasonNumber:    meta.SeasonNumber,
+			EpisodeType:     meta.EpisodeType,
+			Explicit:        meta.Explicit,
+			ImageURL:        meta.ImageURL,
+		}
+
+		// Upsert
+		result := db.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "guid"}},
+			DoUpdates: clause.AssignmentColumns([]string{
+				"feed_guid", "fingerprint", "title", "description", "audio_url", "pub_date", "updated_at",
+				"duration", "duration_seconds", "enclosure_length", "enclosure_type",
+				"episode_number", "season_number", "episode_type", "explicit", "image_url", "removed_at",
+			}),
+		}).Create(&episode)
+
+		if result.Error == nil {
+			if isNew {
+				newGUIDs = append(newGUIDs, guid)
+			} else {
+				updatedCount++
+			}
+			categories[guid] = item.Categories
+			if isNew || existing.Title != episode.Title || existing.Description != episode.Description {
+				reindex = append(reindex, guid)
+			}
+			// 章节和发布方字幕在后台抓取，不阻塞刷新
+			if chaptersURL := findChaptersURL(item); chaptersURL != "" {
+				feedAssetQueue.AddTask(FeedAssetTask{GUID: guid, Kind: FeedAssetChapters, URL: chaptersURL})
+			}
+			// 发布方提供了字幕，且本地还没有字幕，或发布方更换了字幕地址时抓取
+			if link := findTranscriptLink(item); link != nil {
+				if (existing.SrtContent == "" && existing.TranscriptText == "") ||
+					(isPublisherTranscript(&existing) && existing.TranscriptURL != link.URL) {
+					feedAssetQueue.AddTask(FeedAssetTask{GUID: guid, Kind: FeedAssetTranscript, URL: link.URL, Type: link.Type})
+				}
+			}
+		}
+	}
+
+	syncFeedTags(categories)
+	indexEpisodesForSearch(reindex)
+	return newGUIDs, updatedCount
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"log"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+)
+
+// 判断已有节目的音频是否被替换。地址变化但文件大小一致时视为同一文件（如更换 CDN）
+func enclosureChanged(existing *Episode, audioURL string, length int64) bool {
+	if existing.AudioURL == "" || audioURL == "" {
+		return false
+	}
+	if normalizeEnclosureURL(existing.AudioURL) == normalizeEnclosureURL(audioURL) {
+		return false
+	}
+	return existing.EnclosureLength <= 0 || length <= 0 || existing.EnclosureLength != length
+}
+
+// 音频被替换后删除本地缓存，已有字幕标记为可能过期
+func invalidateEpisodeAudio(existing *Episode) {
+	updates := map[string]interface{}{"local_audio_path": ""}
+	if existing.SrtContent != "" {
+		updates["transcript_stale"] = true
+	}
+	if existing.LocalAudioPath != "" {
+		removeCachedAudio(existing.LocalAudioPath)
+	}
+	db.Model(&Episode{}).Where("guid = ?", existing.GUID).Updates(updates)
+	log.Printf("♻️ Enclosure replaced for %q, local audio invalidated", existing.Title)
+}
+
+// 将 feed 中已不存在的节目标记为已下架。
+// 只检查不早于 feed 中最旧条目的节目，避免把因 feed 截断而消失的旧节目误判为下架
+func markRemovedEpisodes(channelID string, items []*gofeed.Item) int {
+	if len(items) == 0 {
+		return 0
+	}
+
+	guids := map[string]bool{}
+	fingerprints := map[string]bool{}
+	var oldest time.Time
+	for _, item := range items {
+		pubDate := time.Now()
+		if item.PublishedParsed != nil {
+			pubDate = *item.PublishedParsed
+		}
+		pubDate = pubDate.UTC()
+		if oldest.IsZero() || pubDate.Before(oldest) {
+			oldest = pubDate
+		}
+
+		audioURL := ""
+		if enc := pickEnclosure(item); enc != nil {
+			audioURL = enc.URL
+		}
+		guids[feedItemGUID(channelID, item, audioURL, pubDate)] = true
+		if fp := episodeFingerprint(channelID, audioURL); fp != "" {
+			fingerprints[fp] = true
+		}
+	}
+
+	var candidates []Episode
+	db.Select("guid", "feed_guid", "fingerprint", "title").
+		Where("channel_id = ? AND removed_at IS NULL AND pub_date >= ?", channelID, oldest).
+		Find(&candidates)
+
+	now := time.Now()
+	removed := 0
+	for _, ep := range candidates {
+		if guids[ep.GUID] || guids[ep.FeedGUID] || fingerprints[ep.Fingerprint] {
+			continue
+		}
+		db.Model(&Episode{}).Where("guid = ?", ep.GUID).Update("removed_at", now)
+		log.Printf("🚫 Episode removed upstream: %q (%s)", ep.Title, ep.GUID)
+		removed++
+	}
+	return removed
+}
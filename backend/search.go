@@ -0,0 +1,399 @@
+package main
+
+import (
+	"encoding/json"
+	"html"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+	// trigram 分词要求每个词至少 3 个字符，更短的查询走 LIKE
+	minFTSTermLength = 3
+	// 片段前后保留的字符数
+	snippetRadius = 60
+	// FTS5 snippet() 的高亮标记，转义 HTML 后再替换为 <mark>
+	ftsMarkOpen  = "\x01"
+	ftsMarkClose = "\x02"
+)
+
+// 搜索索引表：SQLite 为 FTS5 虚拟表，MySQL 为带 FULLTEXT 索引的普通表
+const searchTable = "episode_search"
+
+// 创建搜索索引表，并在后台为尚未索引的节目补建索引
+func initSearchIndex() {
+	var err error
+	switch db.Dialector.Name() {
+	case "sqlite":
+		err = db.Exec(`CREATE VIRTUAL TABLE IF NOT EXISTS ` + searchTable + ` USING fts5(
+			guid UNINDEXED, channel_id UNINDEXED, title, description, transcript, tokenize = 'trigram')`).Error
+	case "mysql":
+		err = db.Exec(`CREATE TABLE IF NOT EXISTS ` + searchTable + ` (
+			guid VARCHAR(191) NOT NULL PRIMARY KEY,
+			channel_id VARCHAR(191) NOT NULL,
+			title TEXT,
+			description MEDIUMTEXT,
+			transcript LONGTEXT,
+			INDEX idx_search_channel (channel_id),
+			FULLTEXT INDEX ft_search (title, description, transcript) WITH PARSER ngram
+		) DEFAULT CHARSET = utf8mb4`).Error
+	}
+	if err != nil {
+		log.Printf("❌ Failed to create search index: %v", err)
+		return
+	}
+	go backfillSearchIndex()
+}
+
+func backfillSearchIndex() {
+	var guids []string
+	db.Model(&Episode{}).Where("guid NOT IN (?)", db.Table(searchTable).Select("guid")).Pluck("guid", &guids)
+	if len(guids) == 0 {
+		return
+	}
+	for start := 0; start < len(guids); start += 100 {
+		end := start + 100
+		if end > len(guids) {
+			end = len(guids)
+		}
+		indexEpisodesForSearch(guids[start:end])
+	}
+	log.Printf("🔎 Indexed %d episode(s) for search", len(guids))
+}
+
+// 字幕转为纯文本，去掉序号和时间轴
+func transcriptPlainText(content string) string {
+	cues := parseSRT(content)
+	if len(cues) == 0 {
+		return content
+	}
+	texts := make([]string, len(cues))
+	for i, cue := range cues {
+		texts[i] = cue.Text
+	}
+	return strings.Join(texts, " ")
+}
+
+// 节目的字幕文本，没有 SRT 时使用无时间轴的发布方字幕
+func episodeTranscriptText(ep Episode) string {
+	if text := transcriptPlainText(ep.SrtContent); text != "" {
+		return text
+	}
+	return ep.TranscriptText
+}
+
+// 节目简介可能是 HTML
+func plainDescription(description string) string {
+	return strings.Join(strings.Fields(html.UnescapeString(tagRe.ReplaceAllString(description, " "))), " ")
+}
+
+// 重建指定节目的索引，节目已删除时只删除索引
+func indexEpisodesForSearch(guids []string) {
+	if len(guids) == 0 {
+		return
+	}
+	var episodes []Episode
+	db.Select("guid", "channel_id", "title", "description", "srt_content", "transcript_text").Where("guid IN ?", guids).Find(&episodes)
+
+	removeEpisodesFromSearch(guids)
+	for _, ep := range episodes {
+		err := db.Exec("INSERT INTO "+searchTable+" (guid, channel_id, title, description, transcript) VALUES (?, ?, ?, ?, ?)",
+			ep.GUID, ep.ChannelID, ep.Title, plainDescription(ep.Description), episodeTranscriptText(ep)).Error
+		if err != nil {
+			log.Printf("❌ Failed to index %s for search: %v", ep.GUID, err)
+		}
+	}
+}
+
+func removeEpisodesFromSearch(guids []string) {
+	if len(guids) > 0 {
+		db.Exec("DELETE FROM "+searchTable+" WHERE guid IN ?", guids)
+	}
+}
+
+func removeChannelFromSearch(channelID string) {
+	db.Exec("DELETE FROM "+searchTable+" WHERE channel_id = ?", channelID)
+}
+
+// 单条搜索结果
+type searchHit struct {
+	Episode Episode `json:"episode"`
+	Score   float64 `json:"score"`   // 越大越相关
+	Snippet string  `json:"snippet"` // 匹配处用 <mark></mark> 标出
+}
+
+type searchRow struct {
+	GUID    string
+	Score   float64
+	Snippet string
+}
+
+// 把用户输入转成 FTS5 查询：每个词作为短语，词之间为 AND
+func ftsQuery(terms []string) string {
+	quoted := make([]string, len(terms))
+	for i, t := range terms {
+		quoted[i] = `"` + strings.ReplaceAll(t, `"`, `""`) + `"`
+	}
+	return strings.Join(quoted, " ")
+}
+
+// 把用户输入转成 MySQL 布尔模式查询：每个词去掉双引号后作为必须出现的短语，
+// 避免 + - < > ( ) ~ * @ 等字符被当作运算符
+func mysqlBooleanQuery(terms []string) string {
+	var quoted []string
+	for _, t := range terms {
+		if t = strings.TrimSpace(strings.ReplaceAll(t, `"`, "")); t != "" {
+			quoted = append(quoted, `+"`+t+`"`)
+		}
+	}
+	return strings.Join(quoted, " ")
+}
+
+// 在文本中找到第一个命中的词，截取前后片段并高亮所有命中
+func buildSnippet(text string, terms []string) string {
+	ranges := termRanges(text, terms)
+	if len(ranges) == 0 {
+		return ""
+	}
+
+	start, end := ranges[0][0], ranges[0][0]
+	for n := 0; n < snippetRadius && start > 0; n++ {
+		_, size := utf8.DecodeLastRuneInString(text[:start])
+		start -= size
+	}
+	for n := 0; n < snippetRadius*2 && end < len(text); n++ {
+		_, size := utf8.DecodeRuneInString(text[end:])
+		end += size
+	}
+	// 把命中范围裁剪到片段内
+	var clipped [][2]int
+	for _, rg := range ranges {
+		from, to := max(rg[0], start), min(rg[1], end)
+		if from < to {
+			clipped = append(clipped, [2]int{from - start, to - start})
+		}
+	}
+	snippet := markRanges(text[start:end], clipped)
+	if start > 0 {
+		snippet = "…" + snippet
+	}
+	if end < len(text) {
+		snippet += "…"
+	}
+	return snippet
+}
+
+// 所有词在原文中的命中范围（字节偏移），不区分大小写，重叠或相邻的范围合并后按位置排序
+func termRanges(text string, terms []string) [][2]int {
+	lower := strings.ToLower(text)
+	var ranges [][2]int
+	for _, term := range terms {
+		if term == "" {
+			continue
+		}
+		// ToLower 可能改变字节长度，此时退回区分大小写的匹配
+		haystack, needle := text, term
+		if lowerTerm := strings.ToLower(term); len(lower) == len(text) && len(lowerTerm) == len(term) {
+			haystack, needle = lower, lowerTerm
+		}
+		for offset := 0; ; {
+			i := strings.Index(haystack[offset:], needle)
+			if i < 0 {
+				break
+			}
+			ranges = append(ranges, [2]int{offset + i, offset + i + len(needle)})
+			offset += i + len(needle)
+		}
+	}
+	sort.Slice(ranges, func(a, b int) bool { return ranges[a][0] < ranges[b][0] })
+
+	var merged [][2]int
+	for _, rg := range ranges {
+		if n := len(merged); n > 0 && rg[0] <= merged[n-1][1] {
+			merged[n-1][1] = max(merged[n-1][1], rg[1])
+			continue
+		}
+		merged = append(merged, rg)
+	}
+	return merged
+}
+
+// 转义 HTML 并用 <mark> 标出 ranges，ranges 需已排序且互不重叠
+func markRanges(text string, ranges [][2]int) string {
+	var b strings.Builder
+	last := 0
+	for _, rg := range ranges {
+		b.WriteString(html.EscapeString(text[last:rg[0]]))
+		b.WriteString("<mark>" + html.EscapeString(text[rg[0]:rg[1]]) + "</mark>")
+		last = rg[1]
+	}
+	b.WriteString(html.EscapeString(text[last:]))
+	return b.String()
+}
+
+// 一次性高亮所有词，返回转义后的 HTML
+func highlightTerms(text string, terms []string) string {
+	return markRanges(text, termRanges(text, terms))
+}
+
+// 转义 FTS5 片段中的 HTML，再把标记替换为 <mark>，与 buildSnippet 的输出一致
+func markFTSSnippet(s string) string {
+	return strings.NewReplacer(ftsMarkOpen, "<mark>", ftsMarkClose, "</mark>").Replace(html.EscapeString(s))
+}
+
+// 按数据库类型执行搜索，返回按相关度排序的 GUID、得分和片段
+// FTS5 的 MATCH 和辅助函数不支持表别名，这里直接使用表名；trigram 下 snippet 的 token 数即字符数
+func runSearch(terms []string, channelIDs interface{}, limit, offset int) ([]searchRow, error) {
+	useFTS := true
+	for _, t := range terms {
+		if utf8.RuneCountInString(t) < minFTSTermLength {
+			useFTS = false
+		}
+	}
+
+	var rows []searchRow
+	base := db.Table(searchTable).
+		Joins("JOIN episodes e ON e.guid = episode_search.guid").
+		Where("e.removed_at IS NULL AND episode_search.channel_id IN (?)", channelIDs)
+
+	switch {
+	case db.Dialector.Name() == "sqlite" && useFTS:
+		// bm25 越小越相关，列权重依次为 guid、channel_id、title、description、transcript
+		err := base.Select("episode_search.guid AS guid, -bm25(episode_search, 0, 0, 10.0, 3.0, 1.0) AS score, "+
+			"snippet(episode_search, -1, ?, ?, '…', 64) AS snippet", ftsMarkOpen, ftsMarkClose).
+			Where("episode_search MATCH ?", ftsQuery(terms)).
+			Order("score DESC").Limit(limit).Offset(offset).Scan(&rows).Error
+		for i := range rows {
+			rows[i].Snippet = markFTSSnippet(rows[i].Snippet)
+		}
+		return rows, err
+	case db.Dialector.Name() == "mysql" && useFTS:
+		against := strings.Join(terms, " ")
+		err := base.Select("episode_search.guid AS guid, MATCH(episode_search.title, episode_search.description, episode_search.transcript) AGAINST (?) AS score", against).
+			Where("MATCH(episode_search.title, episode_search.description, episode_search.transcript) AGAINST (? IN BOOLEAN MODE)", mysqlBooleanQuery(terms)).
+			Order("score DESC").Limit(limit).Offset(offset).Scan(&rows).Error
+		return rows, err
+	}
+
+	// 短查询：逐列 LIKE，标题命中排在前面
+	query := base
+	score := "0"
+	var scoreArgs []interface{}
+	for _, t := range terms {
+		pattern := "%" + strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(t) + "%"
+		query = query.Where(`(episode_search.title LIKE ? ESCAPE '!' OR episode_search.description LIKE ? ESCAPE '!' OR episode_search.transcript LIKE ? ESCAPE '!')`,
+			pattern, pattern, pattern)
+		score += ` + (CASE WHEN episode_search.title LIKE ? ESCAPE '!' THEN 10 ELSE 0 END)`
+		scoreArgs = append(scoreArgs, pattern)
+	}
+	err := query.Select("episode_search.guid AS guid, ("+score+") AS score", scoreArgs...).
+		Order("score DESC, e.pub_date DESC").Limit(limit).Offset(offset).Scan(&rows).Error
+	return rows, err
+}
+
+// 全文搜索 API：/api/search?q=&channel_id=&limit=&offset=
+func searchHandler(w http.ResponseWriter, r *http.Request) {
+	enableCors(&w)
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	q := r.URL.Query()
+	terms := strings.Fields(q.Get("q"))
+	if len(terms) == 0 {
+		http.Error(w, "q is required", http.StatusBadRequest)
+		return
+	}
+	limit := defaultSearchLimit
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			http.Error(w, "invalid limit: "+v, http.StatusBadRequest)
+			return
+		}
+		if n > maxSearchLimit {
+			n = maxSearchLimit
+		}
+		limit = n
+	}
+	offset := 0
+	if v := q.Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			http.Error(w, "invalid offset: "+v, http.StatusBadRequest)
+			return
+		}
+		offset = n
+	}
+
+	user := currentUser(r)
+	channels := userChannelsQuery(user).Select("id")
+	if channelID := q.Get("channel_id"); channelID != "" {
+		channels = channels.Where("id = ?", channelID)
+	}
+
+	rows, err := runSearch(terms, channels, limit, offset)
+	if err != nil {
+		log.Printf("❌ Search failed: %v", err)
+		http.Error(w, "Search failed", http.StatusInternalServerError)
+		return
+	}
+
+	guids := make([]string, len(rows))
+	for i, row := range rows {
+		guids[i] = row.GUID
+	}
+	var episodes []Episode
+	if len(guids) > 0 {
+		db.Where("guid IN ?", guids).Find(&episodes)
+	}
+	byGUID := make(map[string]Episode, len(episodes))
+	for _, ep := range episodes {
+		byGUID[ep.GUID] = ep
+	}
+
+	hits := []searchHit{}
+	for _, row := range rows {
+		ep, ok := byGUID[row.GUID]
+		if !ok {
+			continue
+		}
+		snippet := row.Snippet
+		if snippet == "" {
+			for _, text := range []string{episodeTranscriptText(ep), plainDescription(ep.Description), ep.Title} {
+				if snippet = buildSnippet(text, terms); snippet != "" {
+					break
+				}
+			}
+		}
+		ep.SrtContent = ""
+		hits = append(hits, searchHit{Episode: ep, Score: row.Score, Snippet: snippet})
+	}
+	ordered := make([]Episode, len(hits))
+	for i := range hits {
+		ordered[i] = hits[i].Episode
+	}
+	applyUserEpisodeData(user, ordered)
+	for i := range hits {
+		hits[i].Episode = ordered[i]
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":  true,
+		"query":    q.Get("q"),
+		"results":  hits,
+		"has_more": len(rows) == limit,
+	})
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHighlightTerms(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		terms []string
+		want  string
+	}{
+		{name: "later term inside earlier mark", text: "Bookmark a page & share", terms: []string{"mark", "a"},
+			want: "Book<mark>mark</mark> <mark>a</mark> p<mark>a</mark>ge &amp; sh<mark>a</mark>re"},
+		{name: "terms matching entities", text: "Tom & Jerry < Spike", terms: []string{"amp", "lt", "&"},
+			want: "Tom <mark>&amp;</mark> Jerry &lt; Spike"},
+		{name: "overlapping terms merged", text: "Podcasting", terms: []string{"podcast", "casting"},
+			want: "<mark>Podcasting</mark>"},
+		{name: "case insensitive", text: "Go and GO", terms: []string{"go"}, want: "<mark>Go</mark> and <mark>GO</mark>"},
+		{name: "html in text", text: "<b>bold</b>", terms: []string{"b"},
+			want: "&lt;<mark>b</mark>&gt;<mark>b</mark>old&lt;/<mark>b</mark>&gt;"},
+		{name: "no match", text: "a & b", terms: []string{"zzz"}, want: "a &amp; b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := highlightTerms(tt.text, tt.terms); got != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildSnippet(t *testing.T) {
+	got := buildSnippet("Marks & Spencer: a mark of quality", []string{"mark", "a"})
+	want := "<mark>Mark</mark>s &amp; Spencer: <mark>a</mark> <mark>mark</mark> of qu<mark>a</mark>lity"
+	if got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+
+	long := strings.Repeat("x", 200) + " needle & " + strings.Repeat("y", 200)
+	snippet := buildSnippet(long, []string{"needle"})
+	if !strings.HasPrefix(snippet, "…") || !strings.HasSuffix(snippet, "…") || !strings.Contains(snippet, "<mark>needle</mark> &amp; ") {
+		t.Fatalf("unexpected snippet %q", snippet)
+	}
+	if buildSnippet("nothing here", []string{"zzz"}) != "" {
+		t.Fatal("expected empty snippet without a match")
+	}
+}
+
+func TestMySQLBooleanQuery(t *testing.T) {
+	tests := []struct {
+		terms []string
+		want  string
+	}{
+		{terms: []string{"golang"}, want: `+"golang"`},
+		{terms: []string{"c++", "rust"}, want: `+"c++" +"rust"`},
+		{terms: []string{`say"hi"`}, want: `+"sayhi"`},
+		{terms: []string{"-foo", "(bar)", "baz*", "~qux", "@2", "<x>"}, want: `+"-foo" +"(bar)" +"baz*" +"~qux" +"@2" +"<x>"`},
+		{terms: []string{`"`, "ok"}, want: `+"ok"`},
+		{terms: nil, want: ""},
+	}
+	for _, tt := range tests {
+		if got := mysqlBooleanQuery(tt.terms); got != tt.want {
+			t.Errorf("mysqlBooleanQuery(%q) = %q, want %q", tt.terms, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 字幕分段，由 SRT 解析而来，srt_content 由分段重新生成
+type TranscriptSegment struct {
+	ID          uint     `json:"-" gorm:"primaryKey"`
+	EpisodeGUID string   `json:"-" gorm:"uniqueIndex:idx_segment_position"`
+	Index       int      `json:"index" gorm:"column:cue_index;uniqueIndex:idx_segment_position"`
+	StartMs     int64    `json:"start_ms"`
+	EndMs       int64    `json:"end_ms"`
+	Text        string   `json:"text" gorm:"type:text"`
+	Speaker     string   `json:"speaker,omitempty"`
+	Confidence  *float64 `json:"confidence,omitempty"` // 0-1，来源未提供时为空
+
+	Words []transcriptWord `json:"words,omitempty" gorm:"serializer:json;type:text"`
+}
+
+func (s TranscriptSegment) cue() subtitleCue {
+	return subtitleCue{
+		Start:      time.Duration(s.StartMs) * time.Millisecond,
+		End:        time.Duration(s.EndMs) * time.Millisecond,
+		Text:       s.Text,
+		Speaker:    s.Speaker,
+		Confidence: s.Confidence,
+		Words:      s.Words,
+	}
+}
+
+// 用解析出的字幕替换节目的全部分段
+func replaceTranscriptSegments(tx *gorm.DB, guid string, cues []subtitleCue) error {
+	if err := tx.Where("episode_guid = ?", guid).Delete(&TranscriptSegment{}).Error; err != nil {
+		return err
+	}
+	if len(cues) == 0 {
+		return nil
+	}
+	segments := make([]TranscriptSegment, len(cues))
+	for i, cue := range cues {
+		segments[i] = TranscriptSegment{
+			EpisodeGUID: guid,
+			Index:       i,
+			StartMs:     cue.Start.Milliseconds(),
+			EndMs:       cue.End.Milliseconds(),
+			Text:        cue.Text,
+			Speaker:     cue.Speaker,
+			Confidence:  cue.Confidence,
+			Words:       cue.Words,
+		}
+	}
+	return tx.CreateInBatches(segments, 200).Error
+}
+
+// 读取节目字幕，分段尚未生成时退回解析 srt_content
+func loadTranscriptCues(guid string) []subtitleCue {
+	var segments []TranscriptSegment
+	db.Where("episode_guid = ?", guid).Order("cue_index").Find(&segments)
+	if len(segments) == 0 {
+		var episode Episode
+		db.Select("guid", "srt_content").Where("guid = ?", guid).Limit(1).Find(&episode)
+		return parseSRT(episode.SrtContent)
+	}
+	cues := make([]subtitleCue, len(segments))
+	for i, s := range segments {
+		cues[i] = s.cue()
+	}
+	return cues
+}
+
+// 合并重复节目时迁移分段，目标节目已有字幕时保留目标的分段
+func reassignTranscriptSegments(tx *gorm.DB, fromGUID, toGUID string) error {
+	var count int64
+	tx.Model(&TranscriptSegment{}).Where("episode_guid = ?", toGUID).Count(&count)
+	if count > 0 {
+		return tx.Where("episode_guid = ?", fromGUID).Delete(&TranscriptSegment{}).Error
+	}
+	return tx.Model(&TranscriptSegment{}).Where("episode_guid = ?", fromGUID).Update("episode_guid", toGUID).Error
+}
+
+// 为已有字幕但还没有分段的节目生成分段
+func backfillTranscriptSegments() {
+	var guids []string
+	db.Model(&Episode{}).Where("srt_content <> '' AND guid NOT IN (?)",
+		db.Model(&TranscriptSegment{}).Select("episode_guid")).Pluck("guid", &guids)
+
+	count := 0
+	for _, guid := range guids {
+		var episode Episode
+		if db.Select("guid", "srt_content").Where("guid = ?", guid).Limit(1).Find(&episode).RowsAffected == 0 {
+			continue
+		}
+		cues := parseSRT(episode.SrtContent)
+		if len(cues) == 0 {
+			continue
+		}
+		if err := replaceTranscriptSegments(db, guid, cues); err != nil {
+			log.Printf("❌ Failed to build transcript segments for %s: %v", guid, err)
+			continue
+		}
+		count++
+	}
+	if count > 0 {
+		log.Printf("🧩 Built transcript segments for %d episode(s)", count)
+	}
+	// 早期版本重建 SRT 时把说话人写成 "说话人: " 前缀，按分段重新生成
+	guids = nil
+	db.Model(&Episode{}).Where("srt_content NOT LIKE ? AND guid IN (?)", "%<v %",
+		db.Model(&TranscriptSegment{}).Select("episode_guid").Where("speaker <> ''")).Pluck("guid", &guids)
+	for _, guid := range guids {
+		if err := db.Model(&Episode{}).Where("guid = ?", guid).UpdateColumn("srt_content", formatSRT(loadTranscriptCues(guid))).Error; err != nil {
+			log.Printf("❌ Failed to rewrite speakers in transcript of %s: %v", guid, err)
+		}
+	}
+	if len(guids) > 0 {
+		log.Printf("🧩 Rewrote speaker labels in %d transcript(s)", len(guids))
+	}
+}
+
+// 解析时间参数：120s / 2m30s / 150.5 / 02:30 / 00:02:30
+func parseOffsetParam(key, v string) (*time.Duration, error) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return nil, nil
+	}
+	if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+		return &d, nil
+	}
+	if seconds, err := strconv.ParseFloat(v, 64); err == nil && seconds >= 0 {
+		d := time.Duration(seconds * float64(time.Second))
+		return &d, nil
+	}
+	if strings.Contains(v, ":") {
+		if d, err := parseCueTimestamp(v); err == nil && d >= 0 {
+			return &d, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid %s: %s", key, v)
+}
+
+// 字幕分段 API：/api/episodes/{guid}/transcript?from=&to=，返回与区间重叠的分段
+func transcriptSegmentsHandler(w http.ResponseWriter, r *http.Request, guid string) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	q := r.URL.Query()
+	from, err := parseOffsetParam("from", q.Get("from"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	to, err := parseOffsetParam("to", q.Get("to"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if from != nil && to != nil && *to < *from {
+		http.Error(w, "to must not be earlier than from", http.StatusBadRequest)
+		return
+	}
+
+	var episode Episode
+	if db.Select("guid", "transcript_source", "transcript_text").Where("guid = ?", guid).Limit(1).Find(&episode).RowsAffected == 0 {
+		http.Error(w, "Episode not found", http.StatusNotFound)
+		return
+	}
+
+	query := db.Where("episode_guid = ?", guid)
+	if from != nil {
+		query = query.Where("end_ms >= ?", from.Milliseconds())
+	}
+	if to != nil {
+		query = query.Where("start_ms < ?", to.Milliseconds())
+	}
+	segments := []TranscriptSegment{}
+	query.Order("cue_index").Find(&segments)
+
+	resp := map[string]interface{}{
+		"success":  true,
+		"source":   episode.TranscriptSource,
+		"segments": segments,
+	}
+	// 没有时间轴的发布方字幕只有文本
+	if episode.TranscriptText != "" {
+		resp["text"] = episode.TranscriptText
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
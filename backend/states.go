@@ -0,0 +1,171 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 按当前用户的节目状态筛选，column 为 user_episodes 中的布尔列
+func filterUserEpisodeState(query *gorm.DB, userID uint, column string, value bool) *gorm.DB {
+	matched := db.Model(&UserEpisode{}).Select("episode_guid").Where("user_id = ? AND "+column+" = ?", userID, true)
+	if value {
+		return query.Where("guid IN (?)", matched)
+	}
+	return query.Where("guid NOT IN (?)", matched)
+}
+
+// 旧的播放状态接口，只接受 POST {"played": bool}，由 episodeStateHandler 处理
+func episodePlayedHandler(w http.ResponseWriter, r *http.Request, guid string) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	episodeStateHandler(w, r, guid)
+}
+
+// 节目状态 API：GET 读取，POST 修改 played / starred / archived，未提供的字段保持不变
+func episodeStateHandler(w http.ResponseWriter, r *http.Request, guid string) {
+	var count int64
+	db.Model(&Episode{}).Where("guid = ?", guid).Count(&count)
+	if count == 0 {
+		http.Error(w, "Episode not found", http.StatusNotFound)
+		return
+	}
+
+	ue := getUserEpisode(currentUserID(r), guid)
+	switch r.Method {
+	case "GET":
+	case "POST":
+		var req struct {
+			Played   *bool `json:"played"`
+			Starred  *bool `json:"starred"`
+			Archived *bool `json:"archived"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		now := time.Now()
+		if req.Played != nil && *req.Played != ue.Played {
+			ue.Played = *req.Played
+			ue.PlayedAt = nil
+			if ue.Played {
+				ue.PlayedAt = &now
+			}
+		}
+		if req.Starred != nil && *req.Starred != ue.Starred {
+			ue.Starred = *req.Starred
+			ue.StarredAt = nil
+			if ue.Starred {
+				ue.StarredAt = &now
+			}
+		}
+		if req.Archived != nil {
+			ue.Archived = *req.Archived
+		}
+		if err := saveUserEpisode(&ue); err != nil {
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":    true,
+		"played":     ue.Played,
+		"played_at":  ue.PlayedAt,
+		"starred":    ue.Starred,
+		"starred_at": ue.StarredAt,
+		"archived":   ue.Archived,
+	})
+}
+
+// 批量标记频道节目的播放状态：before 之前发布的节目，或 before_guid 对应节目及更早的节目，都不提供时标记全部
+func channelMarkPlayedHandler(w http.ResponseWriter, r *http.Request, channelID string) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Before     string `json:"before"` // RFC3339 或 YYYY-MM-DD
+		BeforeGUID string `json:"before_guid"`
+		Played     *bool  `json:"played"` // 默认 true
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	before, err := parseDateValue("before", req.Before)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	played := req.Played == nil || *req.Played
+
+	query := db.Model(&Episode{}).Where("channel_id = ?", channelID)
+	if req.BeforeGUID != "" {
+		var anchor Episode
+		if db.Select("guid", "pub_date").Where("guid = ? AND channel_id = ?", req.BeforeGUID, channelID).
+			Limit(1).Find(&anchor).RowsAffected == 0 {
+			http.Error(w, "Episode not found", http.StatusNotFound)
+			return
+		}
+		query = query.Where("pub_date <= ?", anchor.PubDate)
+	} else if before != nil {
+		query = query.Where("pub_date < ?", *before)
+	}
+	var guids []string
+	query.Pluck("guid", &guids)
+
+	if len(guids) > 0 {
+		now := time.Now()
+		var playedAt *time.Time
+		if played {
+			playedAt = &now
+		}
+		userID := currentUserID(r)
+		rows := make([]UserEpisode, len(guids))
+		for i, guid := range guids {
+			rows[i] = UserEpisode{UserID: userID, EpisodeGUID: guid, Played: played, PlayedAt: playedAt}
+		}
+		// 已有状态的节目只在播放状态变化时更新，保留原来的 played_at
+		err := db.Transaction(func(tx *gorm.DB) error {
+			err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "user_id"}, {Name: "episode_guid"}},
+				DoNothing: true,
+			}).CreateInBatches(rows, 200).Error
+			if err != nil {
+				return err
+			}
+			for start := 0; start < len(guids); start += 200 {
+				end := min(start+200, len(guids))
+				err := tx.Model(&UserEpisode{}).
+					Where("user_id = ? AND episode_guid IN ? AND played <> ?", userID, guids[start:end], played).
+					Updates(map[string]interface{}{"played": played, "played_at": playedAt}).Error
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"played":  played,
+		"count":   len(guids),
+	})
+}
@@ -0,0 +1,617 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 标签来源
+const (
+	TagSourceFeed = "feed" // RSS 中的分类，随刷新同步，所有用户可见
+	TagSourceUser = "user" // 用户手动添加
+	TagSourceLLM  = "llm"  // 用户接受的 LLM 建议
+	// 从旧的 tags 列迁移的标签，属于接管旧数据的用户。其中混有 RSS 分类，
+	// 同步 feed 标签时与之重复的部分由 feed 接管
+	TagSourceLegacy = "legacy"
+)
+
+// 标签，slug 为小写名称，用于不区分大小写地去重
+type Tag struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Name      string    `json:"name"`
+	Slug      string    `json:"-" gorm:"uniqueIndex"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// 节目与标签的关联。feed 来源的 user_id 为 0，其余来源属于对应用户（匿名为 0）
+type EpisodeTag struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	EpisodeGUID string    `json:"episode_guid" gorm:"uniqueIndex:idx_episode_tag;index"`
+	TagID       uint      `json:"tag_id" gorm:"uniqueIndex:idx_episode_tag;index"`
+	UserID      uint      `json:"user_id" gorm:"uniqueIndex:idx_episode_tag"`
+	Source      string    `json:"source" gorm:"uniqueIndex:idx_episode_tag"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func normalizeTagName(name string) string {
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// 解析逗号分隔的标签，去掉空值和重复
+func splitTagNames(s string) []string {
+	return uniqueTagNames(strings.Split(s, ","))
+}
+
+func uniqueTagNames(names []string) []string {
+	seen := map[string]bool{}
+	var result []string
+	for _, name := range names {
+		name = normalizeTagName(name)
+		slug := strings.ToLower(name)
+		if name == "" || seen[slug] {
+			continue
+		}
+		seen[slug] = true
+		result = append(result, name)
+	}
+	return result
+}
+
+func findOrCreateTag(tx *gorm.DB, name string) (*Tag, error) {
+	name = normalizeTagName(name)
+	if name == "" {
+		return nil, fmt.Errorf("tag name is required")
+	}
+	tag := Tag{Name: name, Slug: strings.ToLower(name)}
+	err := tx.Where(Tag{Slug: tag.Slug}).FirstOrCreate(&tag).Error
+	return &tag, err
+}
+
+// 当前用户可见的标签关联：feed 标签加上自己的标签
+func visibleEpisodeTags(tx *gorm.DB, userID uint) *gorm.DB {
+	return tx.Where("(episode_tags.source = ? OR episode_tags.user_id = ?)", TagSourceFeed, userID)
+}
+
+// 将某个来源的标签设置为 names，多余的删除，缺少的添加
+func setEpisodeTags(tx *gorm.DB, guid string, userID uint, source string, names []string) error {
+	keep := make([]uint, 0, len(names))
+	for _, name := range uniqueTagNames(names) {
+		tag, err := findOrCreateTag(tx, name)
+		if err != nil {
+			return err
+		}
+		keep = append(keep, tag.ID)
+		err = tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&EpisodeTag{
+			EpisodeGUID: guid, TagID: tag.ID, UserID: userID, Source: source,
+		}).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	stale := tx.Where("episode_guid = ? AND user_id = ? AND source = ?", guid, userID, source)
+	if len(keep) > 0 {
+		stale = stale.Where("tag_id NOT IN ?", keep)
+	}
+	return stale.Delete(&EpisodeTag{}).Error
+}
+
+// 同步 RSS 分类，categories 以 GUID 为键
+func syncFeedTags(categories map[string][]string) {
+	for guid, names := range categories {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := setEpisodeTags(tx, guid, 0, TagSourceFeed, names); err != nil {
+				return err
+			}
+			// MySQL 不允许 DELETE 的子查询引用同一张表，先取出 feed 标签
+			var feedTagIDs []uint
+			tx.Model(&EpisodeTag{}).Where("episode_guid = ? AND source = ?", guid, TagSourceFeed).Pluck("tag_id", &feedTagIDs)
+			if len(feedTagIDs) == 0 {
+				return nil
+			}
+			return tx.Where("episode_guid = ? AND source = ? AND tag_id IN ?", guid, TagSourceLegacy, feedTagIDs).
+				Delete(&EpisodeTag{}).Error
+		})
+		if err != nil {
+			log.Printf("❌ Failed to sync feed tags for %s: %v", guid, err)
+		}
+	}
+}
+
+// 节目的 feed 标签名称
+func feedTagNames(guid string) []string {
+	var names []string
+	db.Model(&EpisodeTag{}).Joins("JOIN tags ON tags.id = episode_tags.tag_id").
+		Where("episode_tags.episode_guid = ? AND episode_tags.source = ?", guid, TagSourceFeed).
+		Order("episode_tags.id").Pluck("tags.name", &names)
+	return names
+}
+
+// 设置用户标签。客户端通常提交完整的标签列表，其中已是 feed 标签的部分不重复保存
+func setUserTags(tx *gorm.DB, userID uint, guid string, names []string) error {
+	feed := map[string]bool{}
+	for _, name := range feedTagNames(guid) {
+		feed[strings.ToLower(name)] = true
+	}
+	var own []string
+	for _, name := range uniqueTagNames(names) {
+		if !feed[strings.ToLower(name)] {
+			own = append(own, name)
+		}
+	}
+	if err := setEpisodeTags(tx, guid, userID, TagSourceUser, own); err != nil {
+		return err
+	}
+	// 提交的是完整列表，迁移来的旧标签以此为准
+	return tx.Where("episode_guid = ? AND user_id = ? AND source = ?", guid, userID, TagSourceLegacy).Delete(&EpisodeTag{}).Error
+}
+
+type episodeTagRow struct {
+	EpisodeGUID string
+	Source      string
+	Name        string
+}
+
+// 读取节目的标签名称，userID 为 nil 时包含所有用户的标签
+func loadEpisodeTags(guids []string, userID *uint) []episodeTagRow {
+	var rows []episodeTagRow
+	if len(guids) == 0 {
+		return rows
+	}
+	query := db.Model(&EpisodeTag{}).Select("episode_tags.episode_guid, episode_tags.source, tags.name").
+		Joins("JOIN tags ON tags.id = episode_tags.tag_id").
+		Where("episode_tags.episode_guid IN ?", guids)
+	if userID != nil {
+		query = visibleEpisodeTags(query, *userID)
+	}
+	query.Order("episode_tags.id").Scan(&rows)
+	return rows
+}
+
+// 填充节目的 feed_tags、user_tags，tags 为两者合并后的逗号分隔字符串，兼容旧客户端
+func applyEpisodeTags(userID uint, episodes []Episode) {
+	guids := make([]string, len(episodes))
+	for i, ep := range episodes {
+		guids[i] = ep.GUID
+	}
+	byGUID := map[string][]episodeTagRow{}
+	for _, row := range loadEpisodeTags(guids, &userID) {
+		byGUID[row.EpisodeGUID] = append(byGUID[row.EpisodeGUID], row)
+	}
+
+	for i := range episodes {
+		ep := &episodes[i]
+		ep.FeedTags, ep.UserTags = []string{}, []string{}
+		var all []string
+		for _, row := range byGUID[ep.GUID] {
+			if row.Source == TagSourceFeed {
+				ep.FeedTags = append(ep.FeedTags, row.Name)
+			} else {
+				ep.UserTags = append(ep.UserTags, row.Name)
+			}
+		}
+		all = append(all, ep.FeedTags...)
+		all = append(all, ep.UserTags...)
+		ep.Tags = strings.Join(uniqueTagNames(all), ",")
+	}
+}
+
+// 按标签筛选节目
+func filterEpisodesByTag(query *gorm.DB, userID uint, name string) *gorm.DB {
+	tagged := visibleEpisodeTags(db.Model(&EpisodeTag{}).Select("episode_tags.episode_guid").
+		Joins("JOIN tags ON tags.id = episode_tags.tag_id").
+		Where("tags.slug = ?", strings.ToLower(normalizeTagName(name))), userID)
+	return query.Where("guid IN (?)", tagged)
+}
+
+// 合并重复节目时迁移标签，目标节目已有的标签直接删除
+func reassignEpisodeTags(tx *gorm.DB, fromGUID, toGUID string) error {
+	var rows []EpisodeTag
+	tx.Where("episode_guid = ?", fromGUID).Find(&rows)
+	for _, row := range rows {
+		var count int64
+		tx.Model(&EpisodeTag{}).Where("episode_guid = ? AND tag_id = ? AND user_id = ? AND source = ?",
+			toGUID, row.TagID, row.UserID, row.Source).Count(&count)
+		if count > 0 {
+			if err := tx.Delete(&EpisodeTag{}, row.ID).Error; err != nil {
+				return err
+			}
+		} else if err := tx.Model(&EpisodeTag{}).Where("id = ?", row.ID).Update("episode_guid", toGUID).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 将用户在 from 标签上的关联移到 to 标签，用于重命名和合并
+func moveUserTag(tx *gorm.DB, userID, fromID, toID uint) (int64, error) {
+	if fromID == toID {
+		return 0, nil
+	}
+	// 已同时带有两个标签的节目，删除旧关联避免重复。MySQL 不允许在 DELETE 中引用同一张表，先查出 GUID
+	var duplicate []string
+	tx.Model(&EpisodeTag{}).Where("tag_id = ? AND user_id = ? AND source <> ?", toID, userID, TagSourceFeed).
+		Pluck("episode_guid", &duplicate)
+	if len(duplicate) > 0 {
+		if err := tx.Where("tag_id = ? AND user_id = ? AND source <> ? AND episode_guid IN ?",
+			fromID, userID, TagSourceFeed, duplicate).Delete(&EpisodeTag{}).Error; err != nil {
+			return 0, err
+		}
+	}
+	result := tx.Model(&EpisodeTag{}).Where("tag_id = ? AND user_id = ? AND source <> ?", fromID, userID, TagSourceFeed).
+		Update("tag_id", toID)
+	return result.RowsAffected, result.Error
+}
+
+// 删除没有任何关联的标签
+func pruneUnusedTags(tx *gorm.DB) {
+	tx.Where("id NOT IN (?)", tx.Model(&EpisodeTag{}).Select("tag_id")).Delete(&Tag{})
+}
+
+// 将旧的 episodes.tags 和 user_episodes.tags 列迁移到标签表，全部成功后删除旧列，失败时下次启动重试。
+// episodes.tags 混有 RSS 分类和用户手动编辑的标签，作为接管旧数据的用户（没有用户时为匿名用户）的
+// legacy 标签迁移，该用户在 user_episodes.tags 中有自己的标签时以后者为准。下次刷新同步 RSS 分类时，
+// 与分类重复的 legacy 标签由 feed 接管，其余保留为用户标签。
+// 直接用 ALTER TABLE 删除，gorm 的 SQLite 迁移器会重建表并丢失索引
+func migrateLegacyTags() {
+	m := db.Migrator()
+	hasEpisodeTags := m.HasColumn(&Episode{}, "tags")
+	hasUserTags := m.HasColumn(&UserEpisode{}, "tags")
+	if !hasEpisodeTags && !hasUserTags {
+		return
+	}
+
+	type legacyKey struct {
+		UserID uint
+		GUID   string
+	}
+	legacy := map[legacyKey]string{}
+	if hasUserTags {
+		var rows []struct {
+			UserID      uint
+			EpisodeGUID string
+			Tags        string
+		}
+		if err := db.Table("user_episodes").Select("user_id, episode_guid, tags").Where("tags IS NOT NULL").Scan(&rows).Error; err != nil {
+			log.Printf("❌ Failed to load user_episodes.tags: %v", err)
+			return
+		}
+		for _, row := range rows {
+			legacy[legacyKey{row.UserID, row.EpisodeGUID}] = row.Tags
+		}
+	}
+	if hasEpisodeTags {
+		// 第一个注册的用户接管了升级前的数据，见 adoptLegacyData
+		var owner User
+		db.Order("id").Limit(1).Find(&owner)
+		var rows []struct {
+			GUID string
+			Tags string
+		}
+		if err := db.Table("episodes").Select("guid, tags").Where("tags <> ''").Scan(&rows).Error; err != nil {
+			log.Printf("❌ Failed to load episodes.tags: %v", err)
+			return
+		}
+		for _, row := range rows {
+			key := legacyKey{owner.ID, row.GUID}
+			if _, ok := legacy[key]; !ok {
+				legacy[key] = row.Tags
+			}
+		}
+	}
+
+	failed := 0
+	for key, tags := range legacy {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			return setEpisodeTags(tx, key.GUID, key.UserID, TagSourceLegacy, splitTagNames(tags))
+		})
+		if err != nil {
+			failed++
+			log.Printf("❌ Failed to migrate tags of %s: %v", key.GUID, err)
+		}
+	}
+	if failed > 0 {
+		log.Printf("⚠️ %d episode(s) failed tag migration, keeping legacy tag columns", failed)
+		return
+	}
+	log.Printf("🏷️ Migrated legacy tags of %d episode(s)", len(legacy))
+
+	if hasEpisodeTags {
+		if err := db.Exec("ALTER TABLE episodes DROP COLUMN tags").Error; err != nil {
+			log.Printf("❌ Failed to drop episodes.tags: %v", err)
+		}
+	}
+	if hasUserTags {
+		if err := db.Exec("ALTER TABLE user_episodes DROP COLUMN tags").Error; err != nil {
+			log.Printf("❌ Failed to drop user_episodes.tags: %v", err)
+		}
+	}
+}
+
+// 标签及使用次数
+type tagSummary struct {
+	ID        uint   `json:"id"`
+	Name      string `json:"name"`
+	Count     int64  `json:"count"`      // 带有该标签的节目数
+	FeedCount int64  `json:"feed_count"` // 其中来自 RSS 分类的数量
+	UserCount int64  `json:"user_count"` // 其中由当前用户添加的数量
+}
+
+// 标签 API：/api/tags、/api/tags/apply、/api/tags/merge、/api/tags/{id}
+func tagsRouter(w http.ResponseWriter, r *http.Request) {
+	enableCors(&w)
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	userID := currentUserID(r)
+	switch {
+	case len(parts) == 2:
+		listTagsHandler(w, r, userID)
+	case len(parts) == 3 && parts[2] == "apply":
+		applyTagsHandler(w, r, userID)
+	case len(parts) == 3 && parts[2] == "merge":
+		mergeTagsHandler(w, r, userID)
+	case len(parts) == 3:
+		id, err := strconv.ParseUint(parts[2], 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid tag id", http.StatusBadRequest)
+			return
+		}
+		var tag Tag
+		if db.Where("id = ?", id).Limit(1).Find(&tag).RowsAffected == 0 {
+			http.Error(w, "Tag not found", http.StatusNotFound)
+			return
+		}
+		tagHandler(w, r, userID, &tag)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// 列出标签及数量，可用 channel_id 限定频道，source=feed|user 限定来源
+func listTagsHandler(w http.ResponseWriter, r *http.Request, userID uint) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	channels := userChannelsQuery(currentUser(r)).Select("id")
+	if channelID := r.URL.Query().Get("channel_id"); channelID != "" {
+		channels = channels.Where("id = ?", channelID)
+	}
+	query := visibleEpisodeTags(db.Model(&EpisodeTag{}), userID).
+		Joins("JOIN tags ON tags.id = episode_tags.tag_id").
+		Joins("JOIN episodes ON episodes.guid = episode_tags.episode_guid").
+		Where("episodes.removed_at IS NULL AND episodes.channel_id IN (?)", channels)
+	switch source := r.URL.Query().Get("source"); source {
+	case "":
+	case TagSourceFeed:
+		query = query.Where("episode_tags.source = ?", TagSourceFeed)
+	case TagSourceUser:
+		query = query.Where("episode_tags.source <> ?", TagSourceFeed)
+	default:
+		http.Error(w, "invalid source: "+source, http.StatusBadRequest)
+		return
+	}
+
+	tags := []tagSummary{}
+	query.Select("tags.id AS id, tags.name AS name, "+
+		"COUNT(DISTINCT episode_tags.episode_guid) AS count, "+
+		"COUNT(DISTINCT CASE WHEN episode_tags.source = ? THEN episode_tags.episode_guid END) AS feed_count, "+
+		"COUNT(DISTINCT CASE WHEN episode_tags.source <> ? THEN episode_tags.episode_guid END) AS user_count",
+		TagSourceFeed, TagSourceFeed).
+		Group("tags.id, tags.name").Order("count DESC, tags.name").Scan(&tags)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"tags":    tags,
+	})
+}
+
+var errTagShared = errors.New("tag is shared with other users or the feed, its case cannot be changed")
+
+// PATCH 重命名、DELETE 删除当前用户的标签，feed 标签随 RSS 同步，不受影响
+func tagHandler(w http.ResponseWriter, r *http.Request, userID uint, tag *Tag) {
+	switch r.Method {
+	case "PATCH":
+		var req struct {
+			Name string `json:"name"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if normalizeTagName(req.Name) == "" {
+			http.Error(w, "name is required", http.StatusBadRequest)
+			return
+		}
+		var target *Tag
+		var moved int64
+		err := db.Transaction(func(tx *gorm.DB) error {
+			var err error
+			if strings.ToLower(normalizeTagName(req.Name)) == tag.Slug {
+				// 只修改大小写会改动共享的标签，只有当前用户使用时才允许
+				var shared int64
+				tx.Model(&EpisodeTag{}).Where("tag_id = ? AND (source = ? OR user_id <> ?)", tag.ID, TagSourceFeed, userID).Count(&shared)
+				if shared > 0 {
+					return errTagShared
+				}
+				target = tag
+				return tx.Model(tag).Update("name", normalizeTagName(req.Name)).Error
+			}
+			if target, err = findOrCreateTag(tx, req.Name); err != nil {
+				return err
+			}
+			if moved, err = moveUserTag(tx, userID, tag.ID, target.ID); err != nil {
+				return err
+			}
+			pruneUnusedTags(tx)
+			return nil
+		})
+		if errors.Is(err, errTagShared) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"tag":     target,
+			"updated": moved,
+		})
+	case "DELETE":
+		var deleted int64
+		err := db.Transaction(func(tx *gorm.DB) error {
+			result := tx.Where("tag_id = ? AND user_id = ? AND source <> ?", tag.ID, userID, TagSourceFeed).Delete(&EpisodeTag{})
+			if result.Error != nil {
+				return result.Error
+			}
+			deleted = result.RowsAffected
+			pruneUnusedTags(tx)
+			return nil
+		})
+		if err != nil {
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"deleted": deleted,
+		})
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// 将多个标签合并到 target，target 不存在时创建
+func mergeTagsHandler(w http.ResponseWriter, r *http.Request, userID uint) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		TagIDs []uint `json:"tag_ids"`
+		Target string `json:"target"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(req.TagIDs) == 0 || normalizeTagName(req.Target) == "" {
+		http.Error(w, "tag_ids and target are required", http.StatusBadRequest)
+		return
+	}
+
+	var target *Tag
+	var moved int64
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if target, err = findOrCreateTag(tx, req.Target); err != nil {
+			return err
+		}
+		for _, id := range req.TagIDs {
+			n, err := moveUserTag(tx, userID, id, target.ID)
+			if err != nil {
+				return err
+			}
+			moved += n
+		}
+		pruneUnusedTags(tx)
+		return nil
+	})
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"tag":     target,
+		"updated": moved,
+	})
+}
+
+// 批量给节目添加或移除当前用户的标签
+func applyTagsHandler(w http.ResponseWriter, r *http.Request, userID uint) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		GUIDs  []string `json:"guids"`
+		Add    []string `json:"add"`
+		Remove []string `json:"remove"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	add, remove := uniqueTagNames(req.Add), uniqueTagNames(req.Remove)
+	if len(req.GUIDs) == 0 || len(add)+len(remove) == 0 {
+		http.Error(w, "guids and add or remove are required", http.StatusBadRequest)
+		return
+	}
+
+	// 与 canAccessEpisode 相同，跳过不存在或所属频道不可访问的节目
+	var guids []string
+	db.Model(&Episode{}).Where("guid IN ? AND channel_id IN (?)", req.GUIDs, userChannelsQuery(currentUser(r)).Select("id")).
+		Pluck("guid", &guids)
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for _, name := range add {
+			tag, err := findOrCreateTag(tx, name)
+			if err != nil {
+				return err
+			}
+			for _, guid := range guids {
+				err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&EpisodeTag{
+					EpisodeGUID: guid, TagID: tag.ID, UserID: userID, Source: TagSourceUser,
+				}).Error
+				if err != nil {
+					return err
+				}
+			}
+		}
+		if len(remove) > 0 {
+			slugs := make([]string, len(remove))
+			for i, name := range remove {
+				slugs[i] = strings.ToLower(name)
+			}
+			err := tx.Where("episode_guid IN ? AND user_id = ? AND source <> ? AND tag_id IN (?)",
+				guids, userID, TagSourceFeed, tx.Model(&Tag{}).Select("id").Where("slug IN ?", slugs)).
+				Delete(&EpisodeTag{}).Error
+			if err != nil {
+				return err
+			}
+			pruneUnusedTags(tx)
+		}
+		return nil
+	})
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"updated": len(guids),
+	})
+}
@@ -0,0 +1,509 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mmcdole/gofeed"
+	"gorm.io/gorm"
+)
+
+// 字幕来源
+const (
+	TranscriptSourceWhisper   = "whisper"
+	TranscriptSourcePublisher = "publisher"
+	TranscriptSourceUpload    = "upload"
+	TranscriptSourceManual    = "manual"
+)
+
+// 单条字幕
+type subtitleCue struct {
+	Start   time.Duration    `json:"start"`
+	End     time.Duration    `json:"end"`
+	Text    string           `json:"text"`
+	Speaker string           `json:"speaker,omitempty"`
+	Words   []transcriptWord `json:"words,omitempty"` // 逐词时间，只有部分来源提供
+	// 识别置信度 0-1，只有部分来源提供
+	Confidence *float64 `json:"confidence,omitempty"`
+}
+
+// 字幕中一个词或短语的时间
+type transcriptWord struct {
+	StartMs int64  `json:"start_ms"`
+	EndMs   int64  `json:"end_ms"`
+	Text    string `json:"text"`
+}
+
+// 保存字幕并标记来源，所有写入 srt_content 的地方都应通过这里或 saveTranscriptCues。
+// 字幕解析为分段保存，srt_content 由分段重新生成；无法解析时原样保存
+func saveTranscript(guid, srtContent, source string) error {
+	cues := parseSRT(srtContent)
+	if len(cues) > 0 {
+		return saveTranscriptCues(guid, cues, source)
+	}
+	return storeTranscript(guid, srtContent, source, nil)
+}
+
+// 保存已解析的字幕，保留 SRT 无法表示的逐词时间
+func saveTranscriptCues(guid string, cues []subtitleCue, source string) error {
+	return storeTranscript(guid, formatSRT(cues), source, cues)
+}
+
+func storeTranscript(guid, srtContent, source string, cues []subtitleCue) error {
+	var first bool
+	err := db.Transaction(func(tx *gorm.DB) error {
+		first = !hasTranscript(tx, guid)
+		err := tx.Model(&Episode{}).Where("guid = ?", guid).Updates(map[string]interface{}{
+			"srt_content":          srtContent,
+			"transcript_source":    source,
+			"transcription_status": "completed",
+			"transcript_stale":     false,
+		}).Error
+		if err != nil {
+			return err
+		}
+		return replaceTranscriptSegments(tx, guid, cues)
+	})
+	if err == nil {
+		indexEpisodesForSearch([]string{guid})
+		// 只在节目第一次有字幕时生成标签建议，编辑或重新转录不再调用 LLM
+		if first {
+			autoSuggestEpisodeTags(guid)
+		}
+	}
+	return err
+}
+
+// 节目是否已有字幕（带时间轴或纯文本）
+func hasTranscript(tx *gorm.DB, guid string) bool {
+	var count int64
+	tx.Model(&Episode{}).Where("guid = ? AND (srt_content <> '' OR transcript_text <> '')", guid).Count(&count)
+	return count > 0
+}
+
+var timestampRe = regexp.MustCompile(`^\s*((?:\d+:)?\d{1,2}:\d{2}[.,]\d{1,3})\s*-->\s*((?:\d+:)?\d{1,2}:\d{2}[.,]\d{1,3})`)
+
+// 解析 00:01:02,345 / 01:02.345 格式的时间戳
+func parseCueTimestamp(s string) (time.Duration, error) {
+	s = strings.Replace(strings.TrimSpace(s), ",", ".", 1)
+	parts := strings.Split(s, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, fmt.Errorf("invalid timestamp: %s", s)
+	}
+	var hours, minutes int
+	var err error
+	if len(parts) == 3 {
+		if hours, err = strconv.Atoi(parts[0]); err != nil {
+			return 0, err
+		}
+		parts = parts[1:]
+	}
+	if minutes, err = strconv.Atoi(parts[0]); err != nil {
+		return 0, err
+	}
+	seconds, err := strconv.ParseFloat(parts[1], 64)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute +
+		time.Duration(seconds*float64(time.Second)+0.5), nil
+}
+
+var (
+	blankLineRe = regexp.MustCompile(`\n\s*\n`)
+	voiceTagRe  = regexp.MustCompile(`^<v(?:\.[^\s>]*)?\s+([^>]+)>`)
+	tagRe       = regexp.MustCompile(`<[^>]*>`)
+)
+
+// 解析 SRT / WebVTT 字幕，两者的 cue 结构基本一致
+func parseSRT(content string) []subtitleCue {
+	content = strings.ReplaceAll(strings.TrimPrefix(content, "\ufeff"), "\r\n", "\n")
+	var cues []subtitleCue
+	for _, block := range blankLineRe.Split(content, -1) {
+		lines := strings.Split(strings.TrimSpace(block), "\n")
+		for i, line := range lines {
+			m := timestampRe.FindStringSubmatch(line)
+			if m == nil {
+				continue
+			}
+			start, err1 := parseCueTimestamp(m[1])
+			end, err2 := parseCueTimestamp(m[2])
+			if err1 != nil || err2 != nil {
+				break
+			}
+			cue := subtitleCue{Start: start, End: end}
+			text := strings.TrimSpace(strings.Join(lines[i+1:], "\n"))
+			if vm := voiceTagRe.FindStringSubmatch(text); vm != nil {
+				cue.Speaker = strings.TrimSpace(vm[1])
+			}
+			cue.Text = strings.TrimSpace(html.UnescapeString(tagRe.ReplaceAllString(text, "")))
+			if cue.Text != "" {
+				cues = append(cues, cue)
+			}
+			break
+		}
+	}
+	return cues
+}
+
+// 解析 Podcasting 2.0 JSON 字幕，逐词的片段会按说话人合并成句
+func parseJSONTranscript(data []byte) ([]subtitleCue, error) {
+	var doc struct {
+		Segments []struct {
+			Speaker   string  `json:"speaker"`
+			StartTime float64 `json:"startTime"`
+			EndTime   float64 `json:"endTime"`
+			Body      string  `json:"body"`
+			// 规范之外的字段，部分生成工具会提供
+			Confidence *float64 `json:"confidence"`
+		} `json:"segments"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid JSON transcript: %v", err)
+	}
+
+	var cues []subtitleCue
+	for _, seg := range doc.Segments {
+		text := strings.TrimSpace(seg.Body)
+		if text == "" {
+			continue
+		}
+		start := time.Duration(seg.StartTime * float64(time.Second))
+		end := time.Duration(seg.EndTime * float64(time.Second))
+
+		if n := len(cues); n > 0 {
+			last := &cues[n-1]
+			lastRune, _ := utf8.DecodeLastRuneInString(last.Text)
+			sentenceEnded := strings.ContainsRune(".?!。？！", lastRune)
+			if last.Speaker == seg.Speaker && !sentenceEnded &&
+				start-last.End < time.Second && end-last.Start < 7*time.Second {
+				// 合并时保留每个片段的时间，供增强 LRC 使用
+				last.Words = append(cueWords(*last), transcriptWord{StartMs: start.Milliseconds(), EndMs: end.Milliseconds(), Text: text})
+				last.Text += " " + text
+				last.End = end
+				// 合并后取较低的置信度
+				if c := clampConfidence(seg.Confidence); c != nil && (last.Confidence == nil || *c < *last.Confidence) {
+					last.Confidence = c
+				}
+				continue
+			}
+		}
+		cues = append(cues, subtitleCue{Start: start, End: end, Text: text, Speaker: seg.Speaker, Confidence: clampConfidence(seg.Confidence)})
+	}
+	return cues, nil
+}
+
+func clampConfidence(c *float64) *float64 {
+	if c == nil || math.IsNaN(*c) {
+		return nil
+	}
+	v := math.Max(0, math.Min(1, *c))
+	return &v
+}
+
+// 解析 Whisper 的 verbose_json 响应，avg_logprob 换算为置信度。
+// 响应不是该格式时返回 false，调用方按 SRT 处理
+func parseWhisperVerboseJSON(data []byte) ([]subtitleCue, bool) {
+	var doc struct {
+		Segments *[]struct {
+			Start      float64  `json:"start"`
+			End        float64  `json:"end"`
+			Text       string   `json:"text"`
+			AvgLogprob *float64 `json:"avg_logprob"`
+		} `json:"segments"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil || doc.Segments == nil {
+		return nil, false
+	}
+	cues := []subtitleCue{}
+	for _, seg := range *doc.Segments {
+		text := strings.TrimSpace(seg.Text)
+		if text == "" {
+			continue
+		}
+		cue := subtitleCue{
+			Start: time.Duration(seg.Start * float64(time.Second)),
+			End:   time.Duration(seg.End * float64(time.Second)),
+			Text:  text,
+		}
+		if seg.AvgLogprob != nil {
+			c := math.Exp(*seg.AvgLogprob)
+			cue.Confidence = clampConfidence(&c)
+		}
+		cues = append(cues, cue)
+	}
+	return cues, true
+}
+
+// 保存 Whisper 的转录结果，返回对应的 SRT。服务不支持 verbose_json 时响应即为 SRT
+func saveWhisperTranscript(guid, response string) (string, error) {
+	cues, ok := parseWhisperVerboseJSON([]byte(response))
+	if !ok {
+		return response, saveTranscript(guid, response, TranscriptSourceWhisper)
+	}
+	return formatSRT(cues), saveTranscriptCues(guid, cues, TranscriptSourceWhisper)
+}
+
+var (
+	blockTagRe  = regexp.MustCompile(`(?i)<\s*(br|/p|/div|/li|/h[1-6])\s*/?>`)
+	scriptTagRe = regexp.MustCompile(`(?is)<(script|style)[^>]*>.*?</(script|style)>`)
+)
+
+// HTML 字幕没有时间轴，每个段落生成一条时间为 0 的字幕
+func parseHTMLTranscript(content string) []subtitleCue {
+	content = scriptTagRe.ReplaceAllString(content, "")
+	content = blockTagRe.ReplaceAllString(content, "\n")
+	content = html.UnescapeString(tagRe.ReplaceAllString(content, ""))
+
+	var cues []subtitleCue
+	for _, line := range strings.Split(content, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			cues = append(cues, subtitleCue{Text: line})
+		}
+	}
+	return cues
+}
+
+// 字幕的逐词时间，没有时把整条字幕视为一个词
+func cueWords(cue subtitleCue) []transcriptWord {
+	if len(cue.Words) > 0 {
+		return cue.Words
+	}
+	return []transcriptWord{{StartMs: cue.Start.Milliseconds(), EndMs: cue.End.Milliseconds(), Text: cue.Text}}
+}
+
+// 00:01:02,345
+func formatSRTTimestamp(d time.Duration) string {
+	if d < 0 {
+		d = 0
+	}
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d,%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
+
+func formatSRT(cues []subtitleCue) string {
+	var b strings.Builder
+	for i, cue := range cues {
+		// 说话人写成 WebVTT 的 <v> 标签，parseSRT 可以还原，不混入字幕文本
+		text := cue.Text
+		if cue.Speaker != "" {
+			text = "<v " + cue.Speaker + ">" + text
+		}
+		fmt.Fprintf(&b, "%d\n%s --> %s\n%s\n\n", i+1, formatSRTTimestamp(cue.Start), formatSRTTimestamp(cue.End), text)
+	}
+	return b.String()
+}
+
+// feed 中声明的字幕链接
+type transcriptLink struct {
+	URL  string
+	Type string
+}
+
+// 按格式的优先级排序：SRT > VTT > JSON > HTML
+func transcriptTypeRank(mimeType string) int {
+	switch strings.ToLower(strings.TrimSpace(mimeType)) {
+	case "application/x-subrip", "application/srt", "text/srt":
+		return 4
+	case "text/vtt":
+		return 3
+	case "application/json":
+		return 2
+	case "text/html":
+		return 1
+	}
+	return 0
+}
+
+// 从 podcast:transcript 标签中选出最合适的字幕
+func findTranscriptLink(item *gofeed.Item) *transcriptLink {
+	var best *transcriptLink
+	for _, e := range item.Extensions["podcast"]["transcript"] {
+		link := &transcriptLink{URL: e.Attrs["url"], Type: e.Attrs["type"]}
+		if link.URL == "" || transcriptTypeRank(link.Type) == 0 {
+			continue
+		}
+		if best == nil || transcriptTypeRank(link.Type) > transcriptTypeRank(best.Type) {
+			best = link
+		}
+	}
+	return best
+}
+
+// 下载并解析发布方字幕
+func fetchPublisherTranscript(link *transcriptLink) ([]subtitleCue, error) {
+	req, err := http.NewRequest("GET", link.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", feedUserAgent)
+
+	client := &http.Client{Timeout: 60 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("transcript returned HTTP %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 20<<20))
+	if err != nil {
+		return nil, err
+	}
+
+	var cues []subtitleCue
+	switch transcriptTypeRank(link.Type) {
+	case 4, 3:
+		cues = parseSRT(string(data))
+	case 2:
+		if cues, err = parseJSONTranscript(data); err != nil {
+			return nil, err
+		}
+	case 1:
+		cues = parseHTMLTranscript(string(data))
+	}
+	if len(cues) == 0 {
+		return nil, fmt.Errorf("transcript is empty")
+	}
+	return cues, nil
+}
+
+// 字幕是否来自发布方：带时间轴的发布方字幕，或只保存为文本的发布方字幕
+func isPublisherTranscript(ep *Episode) bool {
+	return ep.TranscriptSource == TranscriptSourcePublisher || (ep.SrtContent == "" && ep.TranscriptURL != "")
+}
+
+// 抓取发布方字幕并保存，已有其他来源字幕的节目会被跳过。
+// 没有时间轴的字幕（如 HTML）只保存为文本，不写入 srt_content，节目仍可通过 Whisper 转录
+func ingestPublisherTranscript(guid string, link *transcriptLink) {
+	cues, err := fetchPublisherTranscript(link)
+	if err != nil {
+		log.Printf("⚠️ Failed to fetch publisher transcript for %s: %v", guid, err)
+		return
+	}
+	// 没有字幕或原字幕也来自发布方时写入，避免覆盖用户上传或已转录的内容
+	var episode Episode
+	if db.Select("guid", "srt_content", "transcript_source", "transcript_url").Where("guid = ?", guid).Limit(1).Find(&episode).RowsAffected == 0 ||
+		(episode.SrtContent != "" && !isPublisherTranscript(&episode)) {
+		return
+	}
+	if !cuesTimed(cues) && episode.SrtContent != "" {
+		// 新字幕没有时间轴，清掉旧的发布方字幕
+		err := db.Transaction(func(tx *gorm.DB) error {
+			err := tx.Model(&Episode{}).Where("guid = ?", guid).Updates(map[string]interface{}{
+				"srt_content":          "",
+				"transcript_text":      cuesText(cues),
+				"transcript_source":    "",
+				"transcription_status": "",
+				"transcript_url":       link.URL,
+			}).Error
+			if err != nil {
+				return err
+			}
+			return replaceTranscriptSegments(tx, guid, nil)
+		})
+		if err != nil {
+			log.Printf("❌ Failed to save publisher transcript text for %s: %v", guid, err)
+			return
+		}
+		indexEpisodesForSearch([]string{guid})
+		log.Printf("📝 Replaced publisher transcript for %s with untimed text (%s)", guid, link.Type)
+		return
+	}
+	if !cuesTimed(cues) {
+		if err := saveTranscriptText(guid, cuesText(cues)); err != nil {
+			log.Printf("❌ Failed to save publisher transcript text for %s: %v", guid, err)
+			return
+		}
+		db.Model(&Episode{}).Where("guid = ?", guid).Update("transcript_url", link.URL)
+		log.Printf("📝 Saved untimed publisher transcript for %s as text (%s)", guid, link.Type)
+		return
+	}
+	if err := saveTranscriptCues(guid, cues, TranscriptSourcePublisher); err != nil {
+		log.Printf("❌ Failed to save publisher transcript for %s: %v", guid, err)
+		return
+	}
+	db.Model(&Episode{}).Where("guid = ?", guid).Update("transcript_url", link.URL)
+	log.Printf("📝 Saved publisher transcript for %s (%s)", guid, link.Type)
+}
+
+// 是否带有时间轴，HTML 字幕解析出的时间全部为 0
+func cuesTimed(cues []subtitleCue) bool {
+	for _, cue := range cues {
+		if cue.End > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// 字幕转为纯文本，每条一行
+func cuesText(cues []subtitleCue) string {
+	texts := make([]string, len(cues))
+	for i, cue := range cues {
+		texts[i] = cue.Text
+	}
+	return strings.Join(texts, "\n")
+}
+
+// 保存没有时间轴的字幕文本
+func saveTranscriptText(guid, text string) error {
+	first := !hasTranscript(db, guid)
+	err := db.Model(&Episode{}).Where("guid = ?", guid).Update("transcript_text", text).Error
+	if err == nil {
+		indexEpisodesForSearch([]string{guid})
+		if first {
+			autoSuggestEpisodeTags(guid)
+		}
+	}
+	return err
+}
+
+// 早期版本把 HTML 字幕存成了时间全为 0 的 SRT，改为文本保存并允许重新转录
+func migrateUntimedTranscripts() {
+	var episodes []Episode
+	db.Select("guid", "srt_content").
+		Where("transcript_source = ? AND srt_content LIKE ?", TranscriptSourcePublisher, "%00:00:00,000 --> 00:00:00,000%").
+		Find(&episodes)
+
+	migrated := 0
+	for _, ep := range episodes {
+		cues := parseSRT(ep.SrtContent)
+		if len(cues) == 0 || cuesTimed(cues) {
+			continue
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			err := tx.Model(&Episode{}).Where("guid = ?", ep.GUID).Updates(map[string]interface{}{
+				"srt_content":          "",
+				"transcript_text":      cuesText(cues),
+				"transcript_source":    "",
+				"transcription_status": "",
+			}).Error
+			if err != nil {
+				return err
+			}
+			return replaceTranscriptSegments(tx, ep.GUID, nil)
+		})
+		if err != nil {
+			log.Printf("❌ Failed to migrate untimed transcript of %s: %v", ep.GUID, err)
+			continue
+		}
+		indexEpisodesForSearch([]string{ep.GUID})
+		migrated++
+	}
+	if migrated > 0 {
+		log.Printf("📝 Moved %d untimed publisher transcript(s) to text", migrated)
+	}
+}
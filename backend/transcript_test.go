@@ -0,0 +1,56 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestParseWhisperVerboseJSON(t *testing.T) {
+	data := []byte(`{"text":"hi","segments":[
+		{"start":0,"end":1.5,"text":" Hello ","avg_logprob":-0.1},
+		{"start":1.5,"end":2,"text":"  "},
+		{"start":2,"end":3.25,"text":"world"}]}`)
+	cues, ok := parseWhisperVerboseJSON(data)
+	if !ok || len(cues) != 2 {
+		t.Fatalf("got %+v, %v", cues, ok)
+	}
+	if cues[0].Text != "Hello" || cues[0].End != 1500*time.Millisecond {
+		t.Fatalf("first cue = %+v", cues[0])
+	}
+	if cues[0].Confidence == nil || math.Abs(*cues[0].Confidence-math.Exp(-0.1)) > 1e-9 {
+		t.Fatalf("confidence = %v", cues[0].Confidence)
+	}
+	if cues[1].Confidence != nil {
+		t.Fatalf("expected no confidence without avg_logprob, got %v", *cues[1].Confidence)
+	}
+
+	for _, body := range []string{"1\n00:00:00,000 --> 00:00:01,000\nHi\n", `{"text":"no segments"}`, ""} {
+		if _, ok := parseWhisperVerboseJSON([]byte(body)); ok {
+			t.Errorf("expected %q to fall back to SRT", body)
+		}
+	}
+}
+
+func TestParseJSONTranscriptConfidence(t *testing.T) {
+	cues, err := parseJSONTranscript([]byte(`{"segments":[
+		{"speaker":"A","startTime":0,"endTime":0.5,"body":"one","confidence":0.9},
+		{"speaker":"A","startTime":0.5,"endTime":1,"body":"two.","confidence":0.6},
+		{"speaker":"B","startTime":1,"endTime":2,"body":"three","confidence":1.7},
+		{"speaker":"B","startTime":5,"endTime":6,"body":"four"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*float64{ptr(0.6), ptr(1), nil}
+	if len(cues) != len(want) {
+		t.Fatalf("got %d cues: %+v", len(cues), cues)
+	}
+	for i, w := range want {
+		got := cues[i].Confidence
+		if (got == nil) != (w == nil) || (got != nil && *got != *w) {
+			t.Errorf("cue %d confidence = %v, want %v", i, got, w)
+		}
+	}
+}
+
+func ptr(v float64) *float64 { return &v }
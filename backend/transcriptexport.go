@@ -0,0 +1,259 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// 导出格式对应的 Content-Type
+var transcriptExportTypes = map[string]string{
+	"srt":  "application/x-subrip; charset=utf-8",
+	"vtt":  "text/vtt; charset=utf-8",
+	"lrc":  "text/plain; charset=utf-8",
+	"txt":  "text/plain; charset=utf-8",
+	"json": "application/json",
+}
+
+// 整体平移字幕时间，平移后结束时间不大于 0 的字幕被丢弃
+func shiftCues(cues []subtitleCue, offset time.Duration) []subtitleCue {
+	if offset == 0 {
+		return cues
+	}
+	clamp := func(d time.Duration) time.Duration {
+		if d < 0 {
+			return 0
+		}
+		return d
+	}
+	shifted := make([]subtitleCue, 0, len(cues))
+	for _, cue := range cues {
+		if cue.End+offset <= 0 {
+			continue
+		}
+		cue.Start, cue.End = clamp(cue.Start+offset), clamp(cue.End+offset)
+		if len(cue.Words) > 0 {
+			words := make([]transcriptWord, len(cue.Words))
+			for i, w := range cue.Words {
+				w.StartMs = clamp(time.Duration(w.StartMs)*time.Millisecond + offset).Milliseconds()
+				w.EndMs = clamp(time.Duration(w.EndMs)*time.Millisecond + offset).Milliseconds()
+				words[i] = w
+			}
+			cue.Words = words
+		}
+		shifted = append(shifted, cue)
+	}
+	return shifted
+}
+
+// 把时长不足 minDuration 的字幕并入下一条，说话人不同或间隔超过 1 秒时不合并
+func mergeShortCues(cues []subtitleCue, minDuration time.Duration) []subtitleCue {
+	if minDuration <= 0 {
+		return cues
+	}
+	var merged []subtitleCue
+	for _, cue := range cues {
+		if n := len(merged); n > 0 {
+			last := &merged[n-1]
+			if last.End-last.Start < minDuration && last.Speaker == cue.Speaker && cue.Start-last.End < time.Second {
+				// 只有两条都有逐词时间时才保留，否则增强 LRC 退回整行输出
+				if len(last.Words) > 0 && len(cue.Words) > 0 {
+					last.Words = append(last.Words[:len(last.Words):len(last.Words)], cue.Words...)
+				} else {
+					last.Words = nil
+				}
+				last.Text += " " + cue.Text
+				last.End = cue.End
+				continue
+			}
+		}
+		merged = append(merged, cue)
+	}
+	return merged
+}
+
+// 00:01:02.345
+func formatVTTTimestamp(d time.Duration) string {
+	return strings.Replace(formatSRTTimestamp(d), ",", ".", 1)
+}
+
+func formatVTT(cues []subtitleCue) string {
+	escaper := strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+	var b strings.Builder
+	b.WriteString("WEBVTT\n\n")
+	for _, cue := range cues {
+		text := escaper.Replace(cue.Text)
+		if cue.Speaker != "" {
+			text = "<v " + escaper.Replace(cue.Speaker) + ">" + text
+		}
+		fmt.Fprintf(&b, "%s --> %s\n%s\n\n", formatVTTTimestamp(cue.Start), formatVTTTimestamp(cue.End), text)
+	}
+	return b.String()
+}
+
+// 01:02.34，LRC 的分钟数不限两位
+func formatLRCTimestamp(d time.Duration) string {
+	if d < 0 {
+		d = 0
+	}
+	cs := d.Milliseconds() / 10
+	return fmt.Sprintf("%02d:%02d.%02d", cs/6000, cs/100%60, cs%100)
+}
+
+// LRC 歌词，字幕间隔超过 1 秒时插入空行清屏。
+// enhanced 为 true 且有逐词时间时输出增强 LRC：[mm:ss.xx]<mm:ss.xx>词 <mm:ss.xx>词 <mm:ss.xx>
+func formatLRC(cues []subtitleCue, title string, enhanced bool) string {
+	var b strings.Builder
+	if title != "" {
+		fmt.Fprintf(&b, "[ti:%s]\n", strings.ReplaceAll(title, "\n", " "))
+	}
+	for i, cue := range cues {
+		b.WriteString("[" + formatLRCTimestamp(cue.Start) + "]")
+		if cue.Speaker != "" {
+			b.WriteString(cue.Speaker + ": ")
+		}
+		if enhanced && len(cue.Words) > 0 {
+			for j, w := range cue.Words {
+				if j > 0 {
+					b.WriteString(" ")
+				}
+				fmt.Fprintf(&b, "<%s>%s", formatLRCTimestamp(time.Duration(w.StartMs)*time.Millisecond), strings.ReplaceAll(w.Text, "\n", " "))
+			}
+			fmt.Fprintf(&b, " <%s>", formatLRCTimestamp(time.Duration(cue.Words[len(cue.Words)-1].EndMs)*time.Millisecond))
+		} else {
+			b.WriteString(strings.ReplaceAll(cue.Text, "\n", " "))
+		}
+		b.WriteString("\n")
+		if i == len(cues)-1 || cues[i+1].Start-cue.End >= time.Second {
+			b.WriteString("[" + formatLRCTimestamp(cue.End) + "]\n")
+		}
+	}
+	return b.String()
+}
+
+// 纯文本，每条字幕一行，说话人变化时标出说话人
+func formatTranscriptText(cues []subtitleCue) string {
+	var b strings.Builder
+	speaker := ""
+	for _, cue := range cues {
+		if cue.Speaker != "" && cue.Speaker != speaker {
+			b.WriteString(cue.Speaker + ": ")
+		}
+		speaker = cue.Speaker
+		b.WriteString(strings.ReplaceAll(cue.Text, "\n", " ") + "\n")
+	}
+	return b.String()
+}
+
+// Podcasting 2.0 JSON 字幕格式，与 parseJSONTranscript 读取的格式一致
+func formatJSONTranscript(cues []subtitleCue) string {
+	type segment struct {
+		Speaker   string  `json:"speaker,omitempty"`
+		StartTime float64 `json:"startTime"`
+		EndTime   float64 `json:"endTime"`
+		Body      string  `json:"body"`
+	}
+	segments := make([]segment, len(cues))
+	for i, cue := range cues {
+		segments[i] = segment{Speaker: cue.Speaker, StartTime: cue.Start.Seconds(), EndTime: cue.End.Seconds(), Body: cue.Text}
+	}
+	data, _ := json.Marshal(map[string]interface{}{
+		"version":  "1.0.0",
+		"segments": segments,
+	})
+	return string(data)
+}
+
+// 字幕导出：/api/episodes/{guid}/transcript.{srt,vtt,lrc,txt,json}?offset=&merge=&enhanced=&download=
+// offset 整体平移时间（可为负），merge 为最短字幕时长，短于它的字幕并入下一条
+func transcriptExportHandler(w http.ResponseWriter, r *http.Request, guid, format string) {
+	contentType, ok := transcriptExportTypes[format]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	q := r.URL.Query()
+	var offset time.Duration
+	if v := strings.TrimSpace(q.Get("offset")); v != "" {
+		d, err := parseOffsetParam("offset", strings.TrimPrefix(v, "-"))
+		if err != nil {
+			http.Error(w, "invalid offset: "+v, http.StatusBadRequest)
+			return
+		}
+		offset = *d
+		if strings.HasPrefix(v, "-") {
+			offset = -offset
+		}
+	}
+	merge, err := parseOffsetParam("merge", q.Get("merge"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	enhanced, err := parseOptionalBool(q, "enhanced")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	download, err := parseOptionalBool(q, "download")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var episode Episode
+	if db.Select("guid", "title", "transcript_text").Where("guid = ?", guid).Limit(1).Find(&episode).RowsAffected == 0 {
+		http.Error(w, "Episode not found", http.StatusNotFound)
+		return
+	}
+	cues := loadTranscriptCues(guid)
+	if len(cues) == 0 && (format != "txt" || episode.TranscriptText == "") {
+		http.Error(w, "Transcript not found", http.StatusNotFound)
+		return
+	}
+	if merge != nil {
+		cues = mergeShortCues(cues, *merge)
+	}
+	cues = shiftCues(cues, offset)
+
+	var body string
+	switch format {
+	case "srt":
+		body = formatSRT(cues)
+	case "vtt":
+		body = formatVTT(cues)
+	case "lrc":
+		body = formatLRC(cues, episode.Title, enhanced != nil && *enhanced)
+	case "txt":
+		body = formatTranscriptText(cues)
+		// 没有时间轴的字幕只能导出为纯文本
+		if len(cues) == 0 {
+			body = episode.TranscriptText + "\n"
+		}
+	case "json":
+		body = formatJSONTranscript(cues)
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	if download != nil && *download {
+		name := strings.Map(func(r rune) rune {
+			if strings.ContainsRune(`/\:*?"<>|`, r) || r < 0x20 {
+				return '_'
+			}
+			return r
+		}, episode.Title)
+		if name == "" {
+			name = "transcript"
+		}
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name + "." + format}))
+	}
+	w.Write([]byte(body))
+}
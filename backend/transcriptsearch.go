@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultTranscriptContext = 1
+	maxTranscriptContext     = 10
+	maxTranscriptHits        = 500
+)
+
+// 字幕中的一处命中，时间单位为秒
+type transcriptHit struct {
+	Index       int      `json:"index"`
+	StartTime   float64  `json:"start_time"`
+	EndTime     float64  `json:"end_time"`
+	Speaker     string   `json:"speaker,omitempty"`
+	Text        string   `json:"text"`
+	Highlighted string   `json:"highlighted"` // 命中处用 <mark></mark> 标出
+	Before      []string `json:"before"`      // 前面若干条字幕
+	After       []string `json:"after"`       // 后面若干条字幕
+}
+
+// 在字幕中查找同时包含所有词的 cue，不区分大小写
+func searchCues(cues []subtitleCue, terms []string, context int) []transcriptHit {
+	lowerTerms := make([]string, len(terms))
+	for i, t := range terms {
+		lowerTerms[i] = strings.ToLower(t)
+	}
+
+	hits := []transcriptHit{}
+	for i, cue := range cues {
+		text := strings.ToLower(cue.Text)
+		matched := true
+		for _, t := range lowerTerms {
+			if !strings.Contains(text, t) {
+				matched = false
+				break
+			}
+		}
+		if !matched {
+			continue
+		}
+
+		hit := transcriptHit{
+			Index:       i,
+			StartTime:   cue.Start.Seconds(),
+			EndTime:     cue.End.Seconds(),
+			Speaker:     cue.Speaker,
+			Text:        cue.Text,
+			Highlighted: highlightTerms(cue.Text, terms),
+			Before:      []string{},
+			After:       []string{},
+		}
+		for j := i - context; j < i; j++ {
+			if j >= 0 {
+				hit.Before = append(hit.Before, cues[j].Text)
+			}
+		}
+		for j := i + 1; j <= i+context && j < len(cues); j++ {
+			hit.After = append(hit.After, cues[j].Text)
+		}
+		hits = append(hits, hit)
+		if len(hits) >= maxTranscriptHits {
+			break
+		}
+	}
+	return hits
+}
+
+// 字幕子路由：/api/episodes/{guid}/transcript/...
+func episodeTranscriptRouter(w http.ResponseWriter, r *http.Request, guid string, rest []string) {
+	if len(rest) == 0 || (len(rest) == 1 && rest[0] == "") {
+		transcriptSegmentsHandler(w, r, guid)
+		return
+	}
+	if len(rest) == 1 && rest[0] == "search" {
+		transcriptSearchHandler(w, r, guid)
+		return
+	}
+	http.NotFound(w, r)
+}
+
+// 字幕内搜索：/api/episodes/{guid}/transcript/search?q=&context=
+func transcriptSearchHandler(w http.ResponseWriter, r *http.Request, guid string) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	q := r.URL.Query()
+	terms := strings.Fields(q.Get("q"))
+	if len(terms) == 0 {
+		http.Error(w, "q is required", http.StatusBadRequest)
+		return
+	}
+	context := defaultTranscriptContext
+	if v := q.Get("context"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			http.Error(w, "invalid context: "+v, http.StatusBadRequest)
+			return
+		}
+		if n > maxTranscriptContext {
+			n = maxTranscriptContext
+		}
+		context = n
+	}
+
+	var count int64
+	db.Model(&Episode{}).Where("guid = ?", guid).Count(&count)
+	if count == 0 {
+		http.Error(w, "Episode not found", http.StatusNotFound)
+		return
+	}
+
+	hits := searchCues(loadTranscriptCues(guid), terms, context)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"query":   q.Get("q"),
+		"hits":    hits,
+	})
+}
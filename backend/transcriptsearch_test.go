@@ -0,0 +1,21 @@
+package main
+
+import "testing"
+
+func TestSearchCuesHighlight(t *testing.T) {
+	cues := []subtitleCue{
+		{Text: "intro"},
+		{Text: "Mark & Amy"},
+		{Text: "outro"},
+	}
+	hits := searchCues(cues, []string{"mark", "a"}, 1)
+	if len(hits) != 1 {
+		t.Fatalf("got %d hits, want 1", len(hits))
+	}
+	if want := "<mark>Mark</mark> &amp; <mark>A</mark>my"; hits[0].Highlighted != want {
+		t.Fatalf("highlighted = %q, want %q", hits[0].Highlighted, want)
+	}
+	if len(hits[0].Before) != 1 || len(hits[0].After) != 1 {
+		t.Fatalf("unexpected context: %+v", hits[0])
+	}
+}